@@ -1,20 +1,23 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
+	"fmt"
 	"html/template"
 	"io/ioutil"
 	"log"
 	"os"
+	"time"
 )
 
 type SubPage struct {
 	Title     string        `json:"title"`
 	Content   template.HTML `json:"content"`
+	Data      interface{}   `json:"-"` // what the page template renders, for layout pages
 	ShortDesc string
 	FullURL   string
 	Twitter   *TwitterCard
+	Pager     *Pager // set on paged lists
 }
 
 type WebLink struct {
@@ -41,6 +44,7 @@ type GenerateData struct { // Loaded from files and Generated
 	ShortGallery GalleryList
 	GameList     GameList
 	Platforms    []string
+	NextDue      time.Time // when the next scheduled post goes live
 }
 
 type TemplateRoot struct {
@@ -48,129 +52,107 @@ type TemplateRoot struct {
 }
 
 var (
-	genData  *GenerateData
-	RootTemp *template.Template
+	genData *GenerateData
 )
 
-func loadJSONBlob(filename string, jObj interface{}) {
+func loadJSONBlob(filename string, jObj interface{}) error {
 	log.Println("Loading ", filename)
 	jsonBlob, err := os.ReadFile(filename)
-	CheckErr(err)
+	if err != nil {
+		return err
+	}
 
 	err = json.Unmarshal(jsonBlob, jObj)
-	CheckErrContext(err, "Error in JSON ", filename, " - ")
+	if err != nil {
+		return fmt.Errorf("error in JSON %s: %w", filename, err)
+	}
+	return nil
 }
 
-func saveJSONBlob(filename string, jObj interface{}) {
+func saveJSONBlob(filename string, jObj interface{}) error {
 	log.Println("Saving ", filename)
 	b, err := json.MarshalIndent(jObj, "", "  ")
-	CheckErrContext(err, "Error in JSON ", filename, " - ")
+	if err != nil {
+		return fmt.Errorf("error in JSON %s: %w", filename, err)
+	}
 
 	os.Remove(filename)
-	err = ioutil.WriteFile(filename, b, 0777)
-	CheckErr(err)
+	return ioutil.WriteFile(filename, b, 0777)
 }
 
 // //////////////////////////////////////////////////////////////////////////////
-// Generate About
-func GenerateAbout() {
-	os.RemoveAll(publicHtmlRoot + "index.html")
-	aboutIndexTemp, err := template.ParseFiles("Templates/about.html")
-	CheckErr(err)
-
-	// Run Template
-
-	var outBuffer bytes.Buffer
-	err = aboutIndexTemp.Execute(&outBuffer, genData)
-	CheckErrContext(err, "Error in Template ")
-
+// Template Page - a template over the site data inside the root frame
+func renderTemplatePage(tmplFile string, rel string, title string, fullURL string) error {
 	// Write out Frame
 	frameData := &SubPage{
-		Title:   "Claire Blackshaw",
-		FullURL: "/",
-		Content: template.HTML(outBuffer.String()),
+		Title:   title,
+		FullURL: fullURL,
 	}
 
-	f, fileErr := os.Create(publicHtmlRoot + "index.html")
-	CheckErrContext(fileErr, "Error in File ")
-
-	err = RootTemp.Execute(f, frameData)
-	CheckErr(err)
+	buildReport.Output(rel, currentSection, tmplFile)
+	return writePage(rel, tmplFile, genData, frameData)
+}
 
-	f.Close()
+// //////////////////////////////////////////////////////////////////////////////
+// Generate About
+func GenerateAbout() error {
+	return renderTemplatePage("about.html", "index.html", siteConfig.Title, "/")
 }
 
 // //////////////////////////////////////////////////////////////////////////////
-// Generate Data
-func generateDataOnly() {
+// Generate Data - load every section before any page is built
+func generateDataOnly(sections []Section) {
 	genData = &GenerateData{
 		Feed:  BlogList{},
 		Hobby: HobbyList{},
 		Job:   JobList{},
 	}
+	activeSections = sections
 
-	log.Println("Do Jobs...")
-	genData.Job.LoadFromFile()
-	log.Println("Do Feed...")
-	genData.Feed.LoadFromFile()
-	log.Println("Do Hobby...")
-	genData.Hobby.LoadFromFile()
-	LoadFromMicroListFolder()
-	LoadFromGalleryListFolder()
-
-	// Build Short Feed
-	genData.ShortFeed = genData.Feed[1:4]
-	genData.ShortMicro = genData.Feed[:1]
-
-	// Build Game List
-	genData.GameList = BuildFromJobs(&genData.Job)
-
-	// Build Platform List
-	platformMap := make(map[string]int)
-	genData.Platforms = []string{}
-	for _, g := range genData.GameList {
-		for _, p := range g.Platform {
-			_, ok := platformMap[p]
-			if !ok {
-				platformMap[p] = 1
-				genData.Platforms = append(genData.Platforms, p)
-			}
+	// Carry on without whatever failed to load, it is in the report
+	for _, s := range sections {
+		log.Println("Loading", s.Name())
+		if err := s.Load(); err != nil {
+			reportError(s.Name(), "", err)
 		}
 	}
-}
 
-func setupRoot() {
-	var err error
-	RootTemp, err = template.ParseFiles("Templates/root.html")
-	CheckErr(err)
+	genData.setShortFeeds()
 }
 
-func genWebsite() {
-	generateDataOnly()
-
-	setupRoot()
-
-	log.Println("Generating Gallery")
-	GenerateGallery()
+// Build Short Feed - copies, so they follow the feed once it is filtered and sorted
+func (gd *GenerateData) setShortFeeds() {
+	n := len(gd.Feed)
+	gd.ShortFeed = append(BlogList{}, gd.Feed[min(1, n):min(4, n)]...)
+	gd.ShortMicro = append(BlogList{}, gd.Feed[:min(1, n)]...)
+}
 
-	log.Println("Generating Micro")
-	GenerateMicro()
+// Run a section generator, its failure goes in the report
+func generateSection(name string, gen func() error) {
+	log.Println("Generating", name)
+	currentSection = name
+	defer buildReport.Time(name, time.Now())
 
-	log.Println("Generating Blog ")
-	GenerateBlog()
+	if err := gen(); err != nil {
+		reportError(name, "", err)
+	}
+}
 
-	log.Println("Generating Hobby ")
-	GenerateHobby()
+func genWebsite() error {
+	sections, err := enabledSections()
+	if err != nil {
+		return err
+	}
 
-	log.Println("Generating Job ")
-	GenerateJob()
+	resetTemplates()
 
-	log.Println("Generating About ")
-	GenerateAbout()
+	start := time.Now()
+	generateDataOnly(sections)
+	buildReport.Time("load", start)
 
-	log.Println("Generating Feed ")
-	GenerateFeed()
+	for _, s := range sections {
+		generateSection(s.Name(), s.Generate)
+	}
 
-	log.Println("Generating Sitemap ")
-	GenerateSiteMap()
+	return nil
 }
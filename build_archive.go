@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+type ArchiveMonth struct {
+	Year  int
+	Month time.Month
+	Name  string // "April 2019"
+	URL   string
+	Count int
+	Posts BlogList
+}
+
+type ArchiveYear struct {
+	Year   int
+	URL    string
+	Count  int
+	Months []*ArchiveMonth // newest first
+	Posts  BlogList
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Archive - the feed by year and month, it must already be sorted newest first
+func buildArchive(bl BlogList) []*ArchiveYear {
+	var years []*ArchiveYear
+
+	for _, bp := range bl {
+		y, m := bp.Date.Year(), bp.Date.Month()
+
+		if len(years) == 0 || years[len(years)-1].Year != y {
+			years = append(years, &ArchiveYear{
+				Year: y,
+				URL:  fmt.Sprintf("/blog/%04d/", y),
+			})
+		}
+		year := years[len(years)-1]
+
+		if len(year.Months) == 0 || year.Months[len(year.Months)-1].Month != m {
+			year.Months = append(year.Months, &ArchiveMonth{
+				Year:  y,
+				Month: m,
+				Name:  fmt.Sprintf("%s %d", m, y),
+				URL:   fmt.Sprintf("/blog/%04d/%02d/", y, m),
+			})
+		}
+		month := year.Months[len(year.Months)-1]
+
+		year.Posts = append(year.Posts, bp)
+		year.Count++
+		month.Posts = append(month.Posts, bp)
+		month.Count++
+	}
+
+	return years
+}
+
+// Year and month lists at the same place as the post links, and an overview
+func GenerateBlogArchive(bl BlogList) {
+	years := buildArchive(bl)
+
+	for _, y := range years {
+		title := fmt.Sprintf("Blog - %d", y.Year)
+		if err := generateBlogIndex(y.Posts, title, y.URL); err != nil {
+			reportError("Archive "+title, y.URL, err)
+		}
+
+		for _, m := range y.Months {
+			if err := generateBlogIndex(m.Posts, "Blog - "+m.Name, m.URL); err != nil {
+				reportError("Archive "+m.Name, m.URL, err)
+			}
+		}
+	}
+
+	outPath := "blog/archive/index.html"
+	buildReport.Output(outPath, currentSection, siteConfig.SrcPath("blogdata"))
+
+	inputs := buildManifest.InputHash(years)
+	if buildManifest.Fresh(outPath, inputs) {
+		return
+	}
+
+	// Write out Frame
+	frameData := &SubPage{
+		Title:   "Blog Archive",
+		FullURL: "/blog/archive/",
+	}
+
+	if err := writePage(outPath, "archive.html", years, frameData); err != nil {
+		reportError("Archive", outPath, err)
+		return
+	}
+	buildManifest.RecordInputs(outPath, inputs)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Each year with its count, then its months with theirs
+func archiveShape(years []*ArchiveYear) []string {
+	var shape []string
+	for _, y := range years {
+		shape = append(shape, fmt.Sprintf("%s %d", y.URL, y.Count))
+		for _, m := range y.Months {
+			shape = append(shape, fmt.Sprintf("  %s %s %d", m.URL, m.Name, m.Count))
+		}
+	}
+	return shape
+}
+
+func TestBuildArchive(t *testing.T) {
+	tests := []struct {
+		name  string
+		dates []string // newest first, like the feed
+		shape []string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "years and months",
+			dates: []string{"2021-03-05", "2021-03-01", "2021-01-10", "2020-12-31", "2019-04-01"},
+			shape: []string{
+				"/blog/2021/ 3",
+				"  /blog/2021/03/ March 2021 2",
+				"  /blog/2021/01/ January 2021 1",
+				"/blog/2020/ 1",
+				"  /blog/2020/12/ December 2020 1",
+				"/blog/2019/ 1",
+				"  /blog/2019/04/ April 2019 1",
+			},
+		},
+		{
+			name:  "same month in different years",
+			dates: []string{"2020-03-02", "2019-03-01"},
+			shape: []string{
+				"/blog/2020/ 1",
+				"  /blog/2020/03/ March 2020 1",
+				"/blog/2019/ 1",
+				"  /blog/2019/03/ March 2019 1",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bl BlogList
+			for _, d := range tt.dates {
+				date, err := time.Parse("2006-01-02", d)
+				if err != nil {
+					t.Fatal(err)
+				}
+				bl = append(bl, &BlogPost{Key: d, Date: date})
+			}
+
+			years := buildArchive(bl)
+			if shape := archiveShape(years); !reflect.DeepEqual(shape, tt.shape) {
+				t.Errorf("shape = %q, want %q", shape, tt.shape)
+			}
+
+			// Every post is in its year and month, in feed order
+			var yearPosts, monthPosts BlogList
+			for _, y := range years {
+				yearPosts = append(yearPosts, y.Posts...)
+				for _, m := range y.Months {
+					monthPosts = append(monthPosts, m.Posts...)
+				}
+			}
+			if !reflect.DeepEqual(yearPosts, bl) || !reflect.DeepEqual(monthPosts, bl) {
+				t.Errorf("posts not kept in feed order")
+			}
+		})
+	}
+}
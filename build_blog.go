@@ -1,16 +1,16 @@
 package main
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"html/template"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
-	"sync"
+	"strings"
 	"time"
 )
 
@@ -25,6 +25,10 @@ type BlogPost struct {
 	ShortDesc   string    `json:"desc,omitempty"`
 	RawCategory []BlogCat `json:"category"`
 	Class       string    `json:"classname"`
+	Draft       bool      `json:"draft,omitempty"`
+	Series      string    `json:"series,omitempty"`
+	SeriesPart  int       `json:"seriesPart,omitempty"` // order in the series, posts without one go after by date
+	Aliases     []string  `json:"aliases,omitempty"`    // old URLs that redirect here
 
 	Image       string `json:"image,omitempty"`
 	ImageWidth  string `json:"imageWidth,omitempty"`
@@ -35,14 +39,24 @@ type BlogPost struct {
 	Body     template.HTML `json:"-"`
 	DateStr  string        `json:"-"`
 	IsMicro  bool          `json:"-"`
+
+	Prev        *BlogPost     `json:"-"` // older
+	Next        *BlogPost     `json:"-"` // newer
+	CategoryNav []CategoryNav `json:"-"`
+	Related     BlogList      `json:"-"`
+	TOC         []*TOCEntry   `json:"-"`
+	Stats       PostStats     `json:"-"`
+	SeriesNav   *SeriesNav    `json:"-"`
+	Excerpt     template.HTML `json:"-"` // body before a <!--more--> marker
+	HasMore     bool          `json:"-"`
+
+	SourceFile  string `json:"-"` // Body file with front matter
+	FrontMatter bool   `json:"-"` // Only in front matter, not blogData.js
 }
 
 var (
-	blogTemp, blogIndexTemp *template.Template
-
-	regUrlChar     *regexp.Regexp
-	regUrlSpace    *regexp.Regexp
-	regStripMarkup *regexp.Regexp
+	regUrlChar  *regexp.Regexp
+	regUrlSpace *regexp.Regexp
 )
 
 const longformPubStr = "Mon, 02 Jan 2006 15:04:05 -0700"
@@ -51,17 +65,8 @@ const longformPubStr = "Mon, 02 Jan 2006 15:04:05 -0700"
 //
 
 func init() {
-	var err error
-
 	regUrlChar = regexp.MustCompile("[^A-Za-z]")
 	regUrlSpace = regexp.MustCompile(" ")
-	regStripMarkup = regexp.MustCompile("<[^<>]*>")
-
-	blogIndexTemp, err = template.ParseFiles("Templates/blogindex.html")
-	CheckErr(err)
-
-	blogTemp, err = template.ParseFiles("Templates/blogpost.html")
-	CheckErr(err)
 }
 
 // //////////////////////////////////////////////////////////////////////////////
@@ -88,46 +93,201 @@ func (bl *BlogList) Get(key string) *BlogPost {
 	return nil
 }
 
-func (bl *BlogList) LoadFromFile() {
-	loadJSONBlob("blogdata/blogData.js", bl)
+// blogData.js, which can be missing once every post has front matter, then the front matter
+func (bl *BlogList) LoadFromFile() error {
+	err := loadJSONBlob(siteConfig.SrcPath("blogdata", "blogData.js"), bl)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	bl.mergeFrontMatter(func(path string, err error) {
+		reportError("Front matter", path, err)
+	})
+	return nil
 }
 
-func (bl *BlogList) SaveToFile() {
-	for _, v := range *bl {
-		v.SaveBodyToFile()
+// Blog posts go ahead of any micro posts already merged into the feed
+func LoadBlog() error {
+	var bl BlogList
+	err := bl.LoadFromFile()
+	if err != nil {
+		return err
+	}
+
+	published := BlogList{}
+	for _, bp := range bl {
+		// Posts with a bad date or body are left for GenerateBlog to report
+		if bp.FixupDateFromPubStr() == nil && holdBack(bp.Key, bp.Draft, bp.Date) {
+			continue
+		}
+		bp.LoadBodyFromFile()
+		published = append(published, bp)
+	}
+
+	genData.Feed = append(published, genData.Feed...)
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Publishing - drafts and posts dated in the future stay out of the site
+var (
+	buildDrafts bool
+	buildFuture bool
+)
+
+func holdBack(name string, draft bool, date time.Time) bool {
+	if draft && !buildDrafts {
+		log.Println("Holding back draft", name)
+		return true
 	}
-	saveJSONBlob("blogdata/blogData.js", bl)
+
+	if date.After(time.Now()) && !buildFuture {
+		log.Println("Holding back", name, "until", date)
+		if !draft && (genData.NextDue.IsZero() || date.Before(genData.NextDue)) {
+			genData.NextDue = date
+		}
+		return true
+	}
+
+	return false
 }
 
-func (bl *BlogList) GeneratePage() {
-	var outBuffer bytes.Buffer
-	err := blogIndexTemp.Execute(&outBuffer, bl)
-	CheckErrContext(err, "Error in Template ")
+// Newest first, sorted in place as the admin list saves the feed in this order
+func blogFeedPosts() BlogList {
+	sort.Sort(genData.Feed)
+	return genData.Feed
+}
 
-	// Write out Frame
-	frameData := &SubPage{
-		Title:   "Blog",
-		FullURL: "/blog/",
-		Content: template.HTML(outBuffer.String()),
+// Only the index, post bodies are saved one at a time
+// Replace just this post's entry in blogData.js as it is on disk, the feed in memory
+// is missing drafts, future posts and any that failed to load
+func (bp *BlogPost) SaveToIndex() error {
+	if bp.FrontMatter || bp.IsMicro {
+		return nil
+	}
+
+	indexFile := siteConfig.SrcPath("blogdata", "blogData.js")
+	var index BlogList
+	err := loadJSONBlob(indexFile, &index)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	if i := index.find(bp.Key); i >= 0 {
+		index[i] = bp
+	} else {
+		index = append(index, bp)
+	}
+	return saveJSONBlob(indexFile, &index)
+}
+
+func (bl BlogList) find(key string) int {
+	for i, v := range bl {
+		if v.Key == key {
+			return i
+		}
 	}
+	return -1
+}
 
-	f, fileErr := os.Create(publicHtmlRoot + "blog/index.html")
-	CheckErrContext(fileErr, "Error in File ")
+// Everything the rendered pages depend on, including the fields kept out of blogData.js
+func (bl BlogList) buildInputs() []interface{} {
+	inputs := make([]interface{}, len(bl))
+	for i, bp := range bl {
+		inputs[i] = bp.buildInputs()
+	}
+	return inputs
+}
 
-	err = RootTemp.Execute(f, frameData)
-	CheckErr(err)
+func (bl BlogList) cardInputs() []interface{} {
+	inputs := make([]interface{}, len(bl))
+	for i, bp := range bl {
+		inputs[i] = bp.cardInputs()
+	}
+	return inputs
+}
 
-	f.Close()
+func (bl *BlogList) GeneratePage() error {
+	return generateBlogIndex(*bl, "Blog", "/blog/")
+}
+
+// A list of posts over as many pages as siteConfig.PageSize needs
+func generateBlogIndex(bl BlogList, title string, baseURL string) error {
+	pages, pagers := paginate(bl, baseURL)
+	for i, page := range pages {
+		p := pagers[i]
+		outPath := strings.TrimPrefix(p.URL, "/") + "index.html"
+		buildReport.Output(outPath, currentSection, siteConfig.SrcPath("blogdata"))
+
+		inputs := buildManifest.InputHash(title, p, page.buildInputs())
+		if buildManifest.Fresh(outPath, inputs) {
+			continue
+		}
+
+		// Write out Frame
+		frameData := &SubPage{
+			Title:   title,
+			FullURL: p.URL,
+			Pager:   p,
+		}
+		if p.Current > 1 {
+			frameData.Title = fmt.Sprintf("%s - Page %d", title, p.Current)
+		}
+
+		err := writePage(outPath, "blogindex.html", &PagedList{page, p}, frameData)
+		if err != nil {
+			return err
+		}
+
+		buildManifest.RecordInputs(outPath, inputs)
+	}
+
+	return nil
 }
 
 // //////////////////////////////////////////////////////////////////////////////
 // Blog Post
+// Body files are markdown or html, markdown wins if there are both
+func postBodyFile(date time.Time, key string) string {
+	base := siteConfig.SrcPath("blogdata", "post", fmt.Sprintf("%d", date.Year()), key)
+	if _, err := os.Stat(base + ".md"); err == nil {
+		return base + ".md"
+	}
+	return base + ".html"
+}
+
+func (bp *BlogPost) bodyFile() string {
+	if bp.SourceFile != "" {
+		return bp.SourceFile
+	}
+	return postBodyFile(bp.Date, bp.Key)
+}
+
+// Where the post details came from
+func (bp *BlogPost) metaFile() string {
+	if bp.SourceFile != "" {
+		return bp.SourceFile
+	}
+	return siteConfig.SrcPath("blogdata", "blogData.js")
+}
+
 func (bp *BlogPost) LoadBodyFromFile() error {
-	srcFile := fmt.Sprintf("blogdata/post/%d/%s.html", bp.Date.Year(), bp.Key)
-	bodyBytes, err := os.ReadFile(srcFile)
-	CheckErr(err)
+	data, err := os.ReadFile(bp.bodyFile())
+	if err != nil {
+		return err
+	}
 
-	bp.Body = template.HTML(bodyBytes)
+	_, bodyBytes, err := splitFrontMatter(data)
+	if err != nil {
+		return err
+	}
+
+	if filepath.Ext(bp.bodyFile()) == ".md" {
+		bp.Body = MarkdownToHTML(bodyBytes)
+	} else {
+		bp.Body = template.HTML(bodyBytes)
+	}
+	bp.bodyLoaded()
 	return nil
 }
 
@@ -135,132 +295,201 @@ func (bp *BlogPost) SaveBodyToFile() error {
 	if len(bp.Body) < 8 {
 		return errors.New("body is null or less than 8 characters")
 	}
+	if filepath.Ext(bp.bodyFile()) == ".md" {
+		return fmt.Errorf("body is markdown, edit %s instead", bp.bodyFile())
+	}
 
 	// Make Folder
-	destFolder := fmt.Sprintf("blogdata/post/%d", bp.Date.Year())
-	err := os.MkdirAll(destFolder, 077)
+	err := os.MkdirAll(filepath.Dir(bp.bodyFile()), 0777)
 	if err != nil {
 		return err
 	}
 
-	srcFile := fmt.Sprintf("blogdata/post/%d/%s.html", bp.Date.Year(), bp.Key)
+	srcFile := bp.bodyFile()
 
-	os.Remove(srcFile)
-	err = ioutil.WriteFile(srcFile, []byte(bp.Body), 0777)
-	if err != nil {
-		CheckErr(err)
+	// Front matter is written again in the same format with the new details
+	var head []byte
+	if data, err := os.ReadFile(srcFile); err == nil {
+		if fm, _, err := splitFrontMatter(data); err == nil && fm != nil {
+			head, err = bp.formatFrontMatter(frontMatterFormat(data))
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	return nil
+	os.Remove(srcFile)
+	return ioutil.WriteFile(srcFile, append(head, bp.Body...), 0777)
 }
 
-func (bp *BlogPost) FixupDateFromPubStr() {
+func (bp *BlogPost) FixupDateFromPubStr() error {
 	var err error
 
 	bp.Date, err = time.Parse(longformPubStr, bp.Pubdate)
 	if err != nil {
-		CheckErr(err)
+		return err
 	}
 
-	bp.DateStr = fmt.Sprintf("%d %v %d", bp.Date.Day(), bp.Date.Month(), bp.Date.Year())
+	bp.DateStr = bp.Date.Format(dateStrLayout)
 	bp.Link = fmt.Sprintf("/blog/%04d/%02d/%s/", bp.Date.Year(), bp.Date.Month(), bp.Key)
+	return nil
 }
 
 func (bp *BlogPost) SetNewPubDate(newPubDate time.Time) {
 	bp.Date = newPubDate
-	bp.DateStr = fmt.Sprintf("%d %v %d", bp.Date.Day(), bp.Date.Month(), bp.Date.Year())
+	bp.DateStr = bp.Date.Format(dateStrLayout)
 	bp.Link = fmt.Sprintf("/blog/%04d/%02d/%s/", bp.Date.Year(), bp.Date.Month(), bp.Key)
 	bp.Pubdate = bp.Date.Format(longformPubStr)
 }
 
-func (bp *BlogPost) GeneratePage() {
-	var err error
-
-	log.Println(bp.Link)
+func (bp *BlogPost) buildInputs() interface{} {
+	return struct {
+		*BlogPost
+		Category    []BlogCat
+		Body        template.HTML
+		IsMicro     bool
+		Prev        *BlogPost
+		Next        *BlogPost
+		CategoryNav []CategoryNav
+		Related     []interface{}
+		TOC         []*TOCEntry
+		Stats       PostStats
+		SeriesNav   *SeriesNav
+		Excerpt     template.HTML
+	}{bp, bp.Category, bp.Body, bp.IsMicro, bp.Prev, bp.Next, bp.CategoryNav, bp.Related.cardInputs(), bp.TOC, bp.Stats, bp.SeriesNav, bp.Excerpt}
+}
 
-	err = os.MkdirAll(publicHtmlRoot+bp.Link, 0777)
-	CheckErrContext(err, "Error in Mkdir ")
+// What a post card shows, which has fields kept out of blogData.js too
+func (bp *BlogPost) cardInputs() interface{} {
+	return struct {
+		*BlogPost
+		Stats   PostStats
+		Excerpt template.HTML
+		HasMore bool
+	}{bp, bp.Stats, bp.Excerpt, bp.HasMore}
+}
 
+// Pick the image and fill in a description, done for every post before any page
+// renders as pages can show their neighbours
+func (bp *BlogPost) preparePage() {
 	// Get Banner Image Size (if I have one)
+	bannerW, bannerH, bannerErr := -1, -1, error(nil)
 	if len(bp.BannerImage) > 3 {
-		w, h, e := getImageDimension("." + bp.BannerImage)
-		if e != nil {
-			log.Fatalln("Error getting Banner:", bp.Title, "\n>", bp.BannerImage, "\n>", e)
+		bannerW, bannerH, bannerErr = getImageDimension(siteConfig.SrcPath(bp.BannerImage))
+		if bannerErr != nil {
+			// Still build the page with the fallback image, but report it
+			reportWarning(bp.Key, siteConfig.SrcPath(bp.BannerImage), fmt.Errorf("error getting banner: %w", bannerErr))
 		}
+	}
 
+	if bannerErr == nil && bannerW > 0 {
 		bp.Image = bp.BannerImage
-		bp.ImageWidth = fmt.Sprintf("%d", w)
-		bp.ImageHeight = fmt.Sprintf("%d", h)
+		bp.ImageWidth = fmt.Sprintf("%d", bannerW)
+		bp.ImageHeight = fmt.Sprintf("%d", bannerH)
 	} else if len(bp.SmallImage) > 3 {
 		bp.Image = bp.SmallImage
 		bp.ImageWidth, bp.ImageHeight = "120", "120"
 	} else {
-		bp.Image = "/images/fp_twitter_tiny.png"
+		bp.Image = siteConfig.DefaultImage
 		bp.ImageWidth, bp.ImageHeight = "120", "120"
 	}
 
-	var outBuffer bytes.Buffer
-	err = blogTemp.Execute(&outBuffer, bp)
-	CheckErr(err)
-
-	blogBody := template.HTML(outBuffer.String())
-
 	// Twitter Card
 	if len(bp.ShortDesc) < 4 {
-		// Build Desc
-		sum := regStripMarkup.ReplaceAllString(string(bp.Body), " ")
-		if len(sum) > 200 {
-			sum = sum[0:200]
-		}
-
-		bp.ShortDesc = sum
+		bp.ShortDesc = bp.summary(200)
 	}
 
+	_, bp.TOC = addHeadingAnchors(bp.Body)
+}
+
+func (bp *BlogPost) GeneratePage() error {
+	var err error
+
 	tc := &TwitterCard{
 		Card:        "summary",
-		Site:        "@EvilKimau",
+		Site:        siteConfig.Social.Twitter,
 		Title:       bp.Title,
 		Description: bp.ShortDesc,
-		Image:       "/images/fp_twitter_tiny.png",
+		Image:       siteConfig.DefaultImage,
 	}
 
-	if len(bp.BannerImage) > 3 {
+	if len(bp.BannerImage) > 3 && bp.Image == bp.BannerImage {
 		tc.Card = "summary_large_image"
 		tc.Image = bp.BannerImage
 	} else if len(bp.SmallImage) > 3 {
 		tc.Image = bp.SmallImage
 	}
 
+	outPath := bp.Link + "index.html"
+	source := bp.bodyFile()
+	if bp.IsMicro {
+		source = siteConfig.SrcPath("microdata")
+	}
+	buildReport.Output(outPath, currentSection, source)
+	buildReport.Post(bp)
+
+	inputs := buildManifest.InputHash(bp.buildInputs())
+	if buildManifest.Fresh(outPath, inputs) {
+		return nil
+	}
+
+	log.Println(bp.Link)
+
 	// Write out Frame
 	frameData := &SubPage{
 		Title:     bp.Title,
 		FullURL:   bp.Link,
 		ShortDesc: bp.ShortDesc,
-		Content:   blogBody,
 		Twitter:   tc,
 	}
 
-	f, fileErr := os.Create(publicHtmlRoot + bp.Link + "index.html")
-	if fileErr != nil {
-		log.Fatalln("Error in File ", fileErr)
-	}
+	// The page gets heading anchors, the post keeps its body as written for the editor
+	page := *bp
+	page.Body, _ = addHeadingAnchors(bp.Body)
 
-	// Note: Don't like the fact we reference RootTemp here
-	err = RootTemp.Execute(f, frameData)
-	CheckErr(err)
+	err = writePage(outPath, "blogpost.html", &page, frameData)
+	if err != nil {
+		return err
+	}
 
-	f.Close()
+	if bp.IsMicro {
+		buildManifest.RecordInputs(outPath, inputs)
+	} else {
+		buildManifest.RecordInputs(outPath, inputs, bp.bodyFile())
+	}
+	return nil
 }
 
 // //////////////////////////////////////////////////////////////////////////////
 // Entry Point
-func GenerateBlog() {
+func GenerateBlog() error {
 	var err error
-	var wg sync.WaitGroup
 
-	os.RemoveAll(publicHtmlRoot + "blog/")
-	err = os.MkdirAll(publicHtmlRoot+"blog/", 0777)
-	CheckErrContext(err, "Unable to make folder")
+	err = makeOutputDir("blog/")
+	if err != nil {
+		return err
+	}
+
+	// Drop posts that can't be built, they go in the report
+	goodPosts := BlogList{}
+	for _, v := range genData.Feed {
+		if err := v.FixupDateFromPubStr(); err != nil {
+			reportError(v.Key, v.metaFile(), err)
+			continue
+		}
+		if len(v.Body) < 1 {
+			if err := v.LoadBodyFromFile(); err != nil {
+				reportError(v.Key, v.bodyFile(), err)
+				continue
+			}
+		}
+		goodPosts = append(goodPosts, v)
+	}
+	genData.Feed = goodPosts
+
+	// Sorted before the categories are gathered so their pages are in the same order
+	sort.Sort(genData.Feed)
+	genData.setShortFeeds()
 
 	// Gather Catergories and filter out single use catergories
 	catMap := make(map[BlogCat]BlogList)
@@ -281,52 +510,117 @@ func GenerateBlog() {
 				v.Category = append(v.Category, c)
 			}
 		}
+	}
+	log.Println("Removed ", removedCat)
 
-		v.FixupDateFromPubStr()
-		if len(v.Body) < 1 {
-			err := v.LoadBodyFromFile()
-			if err != nil {
-				CheckErr(err)
-				return
-			}
+	linkBlogPosts(genData.Feed)
+	series := linkSeries(genData.Feed)
+
+	runParallel(len(genData.Feed), func(i int) {
+		genData.Feed[i].preparePage()
+	})
+
+	runParallel(len(genData.Feed), func(i int) {
+		bp := genData.Feed[i]
+		if err := bp.GeneratePage(); err != nil {
+			reportError(bp.Key, bp.Link, err)
 		}
+	})
 
-		v.GeneratePage()
+	if err := genData.Feed.GeneratePage(); err != nil {
+		reportError("Blog index", "blog/index.html", err)
 	}
-	log.Println("Removed ", removedCat)
+	GenerateBlogArchive(genData.Feed)
+	GenerateBlogSeries(series)
 
-	sort.Sort(genData.Feed)
-	genData.Feed.GeneratePage()
+	cats := make([]BlogCat, 0, len(catMap))
+	for k := range catMap {
+		cats = append(cats, k)
+	}
+	sort.Slice(cats, func(i, j int) bool { return cats[i] < cats[j] })
 
-	for k, v := range catMap {
-		GenerateBlogCatergoryPage(k, &v)
+	runParallel(len(cats), func(i int) {
+		blist := catMap[cats[i]]
+		if err := GenerateBlogCatergoryPage(cats[i], &blist); err != nil {
+			reportError("Category "+string(cats[i]), "blog/cat/"+cats[i].UrlVer()+"/", err)
+		}
+	})
+
+	if err := GenerateBlogCategoryIndex(cats, catMap); err != nil {
+		reportError("Category index", "blog/cat/index.html", err)
 	}
 
-	wg.Wait()
+	return nil
 }
 
-func GenerateBlogCatergoryPage(cat BlogCat, blist *BlogList) {
-	var err error
-	var outBuffer bytes.Buffer
+func GenerateBlogCatergoryPage(cat BlogCat, blist *BlogList) error {
+	baseURL := "/blog/cat/" + cat.UrlVer() + "/"
+	err := generateBlogIndex(*blist, "Blog - "+string(cat), baseURL)
+	if err != nil {
+		return err
+	}
+
+	return writeFeed(strings.TrimPrefix(baseURL, "/")+"rss.xml", siteConfig.Feed.Title+" - "+string(cat), baseURL, *blist)
+}
 
-	err = blogIndexTemp.Execute(&outBuffer, blist)
-	CheckErrContext(err, "Error in Template ")
+// //////////////////////////////////////////////////////////////////////////////
+// Category Index
+type CategoryInfo struct {
+	Name    BlogCat
+	URL     string
+	FeedURL string
+	Count   int
+	Weight  int // 1 to 10 between the smallest and biggest category, for tag clouds
+}
 
-	// Write out Frame
-	frameData := &SubPage{
-		Title:   "Blog - " + string(cat),
-		FullURL: "/blog/cat/" + cat.UrlVer() + "/",
-		Content: template.HTML(outBuffer.String()),
+func buildCategoryList(cats []BlogCat, catMap map[BlogCat]BlogList) []CategoryInfo {
+	lo, hi := -1, 0
+	for _, c := range cats {
+		n := len(catMap[c])
+		hi = max(hi, n)
+		if lo < 0 || n < lo {
+			lo = n
+		}
 	}
 
-	err = os.MkdirAll(publicHtmlRoot+"blog/cat/"+cat.UrlVer(), 0777)
-	CheckErrContext(err, "Error in Mkdir ")
+	list := make([]CategoryInfo, len(cats))
+	for i, c := range cats {
+		list[i] = CategoryInfo{
+			Name:    c,
+			URL:     "/blog/cat/" + c.UrlVer() + "/",
+			FeedURL: "/blog/cat/" + c.UrlVer() + "/rss.xml",
+			Count:   len(catMap[c]),
+			Weight:  10,
+		}
+		if hi > lo {
+			list[i].Weight = 1 + 9*(list[i].Count-lo)/(hi-lo)
+		}
+	}
+	return list
+}
 
-	f, fileErr := os.Create(publicHtmlRoot + "blog/cat/" + cat.UrlVer() + "/index.html")
-	CheckErrContext(fileErr, "Error in File ")
+func GenerateBlogCategoryIndex(cats []BlogCat, catMap map[BlogCat]BlogList) error {
+	list := buildCategoryList(cats, catMap)
 
-	err = RootTemp.Execute(f, frameData)
-	CheckErr(err)
+	outPath := "blog/cat/index.html"
+	buildReport.Output(outPath, currentSection, siteConfig.SrcPath("blogdata"))
 
-	f.Close()
+	inputs := buildManifest.InputHash(list)
+	if buildManifest.Fresh(outPath, inputs) {
+		return nil
+	}
+
+	// Write out Frame
+	frameData := &SubPage{
+		Title:   "Blog Categories",
+		FullURL: "/blog/cat/",
+	}
+
+	err := writePage(outPath, "categories.html", list, frameData)
+	if err != nil {
+		return err
+	}
+
+	buildManifest.RecordInputs(outPath, inputs)
+	return nil
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSaveToIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		post  *BlogPost
+		keys  []string
+		title string // title of the saved post's entry after, "" if it shouldn't be there
+	}{
+		{"entry replaced in place", &BlogPost{Key: "live", Title: "Edited"}, []string{"draft", "live", "future"}, "Edited"},
+		{"missing entry added", &BlogPost{Key: "new", Title: "New"}, []string{"draft", "live", "future", "new"}, "New"},
+		{"front matter only", &BlogPost{Key: "fm", Title: "FM", FrontMatter: true}, []string{"draft", "live", "future"}, ""},
+		{"micro", &BlogPost{Key: "micro", Title: "Micro", IsMicro: true}, []string{"draft", "live", "future"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSite(t)
+			indexFile := siteConfig.SrcPath("blogdata", "blogData.js")
+			writeTestFiles(t, siteConfig.SrcPath("blogdata"), map[string]string{"blogData.js": `[
+				{"key": "draft", "title": "Draft", "draft": true},
+				{"key": "live", "title": "Live", "desc": "kept"},
+				{"key": "future", "title": "Future", "pubDate": "Fri, 01 Jan 2100 00:00:00 +0000"}
+			]`})
+
+			if err := tt.post.SaveToIndex(); err != nil {
+				t.Fatal(err)
+			}
+
+			var index BlogList
+			if err := loadJSONBlob(indexFile, &index); err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for _, bp := range index {
+				keys = append(keys, bp.Key)
+			}
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("keys = %v, want %v", keys, tt.keys)
+			}
+
+			if i := index.find(tt.post.Key); tt.title == "" && i >= 0 {
+				t.Errorf("%s was written to the index", tt.post.Key)
+			} else if tt.title != "" && (i < 0 || index[i].Title != tt.title) {
+				t.Errorf("%s not saved with title %q", tt.post.Key, tt.title)
+			}
+			if d := index[0]; !d.Draft || d.Title != "Draft" {
+				t.Errorf("draft entry changed: %+v", d)
+			}
+		})
+	}
+}
+
+func TestBuildCategoryList(t *testing.T) {
+	tests := []struct {
+		name    string
+		counts  []int // posts in each category, in order
+		weights []int
+	}{
+		{"empty", nil, nil},
+		{"one category", []int{4}, []int{10}},
+		{"all the same", []int{3, 3, 3}, []int{10, 10, 10}},
+		{"smallest to biggest", []int{1, 10}, []int{1, 10}},
+		{"in between", []int{2, 12, 7, 3}, []int{1, 10, 5, 1}},
+		{"empty category", []int{0, 5, 10}, []int{1, 5, 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cats []BlogCat
+			catMap := make(map[BlogCat]BlogList)
+			for i, n := range tt.counts {
+				c := BlogCat(fmt.Sprintf("Cat %d", i))
+				cats = append(cats, c)
+				catMap[c] = make(BlogList, n)
+			}
+
+			list := buildCategoryList(cats, catMap)
+
+			var weights []int
+			for i, info := range list {
+				weights = append(weights, info.Weight)
+				if info.Name != cats[i] || info.Count != tt.counts[i] {
+					t.Errorf("%d: got %s with %d posts", i, info.Name, info.Count)
+				}
+			}
+			if !reflect.DeepEqual(weights, tt.weights) {
+				t.Errorf("weights = %v, want %v", weights, tt.weights)
+			}
+		})
+	}
+}
+
+func TestBuildCategoryListURLs(t *testing.T) {
+	list := buildCategoryList([]BlogCat{"Game Dev!"}, map[BlogCat]BlogList{})
+	if info := list[0]; info.URL != "/blog/cat/GameDev/" || info.FeedURL != "/blog/cat/GameDev/rss.xml" {
+		t.Errorf("got %s and %s", info.URL, info.FeedURL)
+	}
+}
+
+func TestBuildInputsFollowCards(t *testing.T) {
+	m := &BuildManifest{Global: "g"}
+	related := &BlogPost{Key: "related", Title: "Related"}
+	bp := &BlogPost{Key: "post", Related: BlogList{related}}
+
+	tests := []struct {
+		name   string
+		change func()
+	}{
+		{"reading time", func() { related.Stats.ReadingTime++ }},
+		{"excerpt", func() { related.Excerpt += "<p>more</p>" }},
+		{"read more", func() { related.HasMore = !related.HasMore }},
+		{"title", func() { related.Title += "!" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, cards := m.InputHash(bp.buildInputs()), m.InputHash(bp.Related.cardInputs())
+			tt.change()
+			if m.InputHash(bp.buildInputs()) == post {
+				t.Error("post page still looks fresh")
+			}
+			if m.InputHash(bp.Related.cardInputs()) == cards {
+				t.Error("list of cards still looks fresh")
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// CategoryNav - the posts either side of a post within one of its categories
+type CategoryNav struct {
+	Category BlogCat
+	Prev     *BlogPost // older
+	Next     *BlogPost // newer
+}
+
+const (
+	relatedPostCount = 5
+	relatedTermCount = 25 // most used words of each post compared for related posts
+)
+
+var relatedStopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`about after again also because been before being could
+		does doing down during each even from have here into just like made make many more most
+		much only other over really same should some such than that their them then there these
+		they thing things think this those through very were what when where which while will
+		with would your`) {
+		relatedStopWords[w] = true
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Navigation - the feed must be sorted newest first and have its categories filtered
+func linkBlogPosts(bl BlogList) {
+	byCat := make(map[BlogCat]BlogList)
+	for i, bp := range bl {
+		bp.Prev, bp.Next = nil, nil
+		if i+1 < len(bl) {
+			bp.Prev = bl[i+1]
+		}
+		if i > 0 {
+			bp.Next = bl[i-1]
+		}
+
+		for _, c := range bp.Category {
+			byCat[c] = append(byCat[c], bp)
+		}
+	}
+
+	for _, bp := range bl {
+		bp.CategoryNav = []CategoryNav{}
+		for _, c := range bp.Category {
+			nav := CategoryNav{Category: c}
+			list := byCat[c]
+			for i, p := range list {
+				if p != bp {
+					continue
+				}
+				if i+1 < len(list) {
+					nav.Prev = list[i+1]
+				}
+				if i > 0 {
+					nav.Next = list[i-1]
+				}
+			}
+			bp.CategoryNav = append(bp.CategoryNav, nav)
+		}
+	}
+
+	relateBlogPosts(bl)
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Related Posts - scored by shared categories, then by shared common words
+func postTerms(bp *BlogPost) map[string]bool {
+	counts := make(map[string]int)
+	for _, w := range strings.FieldsFunc(strings.ToLower(plainify(bp.Body)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(w)) >= 4 && !relatedStopWords[w] {
+			counts[w]++
+		}
+	}
+
+	words := make([]string, 0, len(counts))
+	for w := range counts {
+		words = append(words, w)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+
+	terms := make(map[string]bool)
+	for _, w := range words[:min(len(words), relatedTermCount)] {
+		terms[w] = true
+	}
+	return terms
+}
+
+func relatedScore(a, b *BlogPost, termsA, termsB map[string]bool) int {
+	score := 0
+	for _, ca := range a.Category {
+		for _, cb := range b.Category {
+			if ca == cb {
+				score += 3
+			}
+		}
+	}
+
+	for t := range termsA {
+		if termsB[t] {
+			score++
+		}
+	}
+	return score
+}
+
+func relateBlogPosts(bl BlogList) {
+	terms := make([]map[string]bool, len(bl))
+	runParallel(len(bl), func(i int) {
+		terms[i] = postTerms(bl[i])
+	})
+
+	runParallel(len(bl), func(i int) {
+		type scored struct {
+			post  *BlogPost
+			score int
+		}
+
+		var candidates []scored
+		for j, other := range bl {
+			if j == i {
+				continue
+			}
+			if s := relatedScore(bl[i], other, terms[i], terms[j]); s > 0 {
+				candidates = append(candidates, scored{other, s})
+			}
+		}
+
+		// Stable keeps the newest first on a tie
+		sort.SliceStable(candidates, func(a, b int) bool { return candidates[a].score > candidates[b].score })
+
+		bl[i].Related = BlogList{}
+		for _, c := range candidates[:min(len(candidates), relatedPostCount)] {
+			bl[i].Related = append(bl[i].Related, c.post)
+		}
+	})
+}
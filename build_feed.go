@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 
 	"strings"
 )
@@ -59,8 +58,8 @@ type Enclosure struct {
 func blogPostToItem(post *BlogPost) Item {
 	return Item{
 		Title:       post.Title,
-		Link:        "https://claire-blackshaw.com" + post.Link,
-		Guid:        "https://claire-blackshaw.com" + post.Link,
+		Link:        siteConfig.AbsURL(post.Link),
+		Guid:        siteConfig.AbsURL(post.Link),
 		PubDate:     post.Pubdate,
 		Description: post.ShortDesc,
 		Enclosure:   createEnclosure(post.BannerImage),
@@ -91,12 +90,12 @@ func createEnclosure(url string) *Enclosure {
 
 	// Prepare the Enclosure object
 	enc := &Enclosure{
-		URL:  "https://claire-blackshaw.com" + url,
+		URL:  siteConfig.AbsURL(url),
 		Type: mimeType,
 	}
 
 	// Update the length based on the file size
-	info, err := os.Stat("." + url)
+	info, err := os.Stat(siteConfig.SrcPath(url))
 	if err != nil {
 		fmt.Println("Error fetching file size:", err, url)
 		enc.Length = "0" // Default to "0" if unable to determine size
@@ -110,34 +109,37 @@ func createEnclosure(url string) *Enclosure {
 // //////////////////////////////////////////////////////////////////////////////
 // Generate Feed
 func GenerateFeed() error {
-	num_posts := min(len(genData.Feed), 30)
+	return writeFeed("rss.xml", siteConfig.Feed.Title, "/", activeFeedPosts())
+}
+
+// The newest 30 posts as RSS at rel, linking back to the page at link
+func writeFeed(rel string, title string, link string, posts BlogList) error {
+	num_posts := min(len(posts), 30)
 
 	rss := RSS{
 		Version: "2.0",
 		XMLNS:   "http://www.w3.org/2005/Atom",
 		Channel: Channel{
-			Title: "CBs GameDev Blog",
-			Link:  "https://claire-blackshaw.com/",
+			Title: title,
+			Link:  siteConfig.AbsURL(link),
 			Image: ImageHeader{
-				URL:   "https://claire-blackshaw.com/images/TitleBoard_Square.png",
-				Link:  "https://claire-blackshaw.com/",
-				Title: "CBs GameDev Blog",
+				URL:   siteConfig.AbsURL(siteConfig.Feed.Image),
+				Link:  siteConfig.AbsURL(link),
+				Title: title,
 			},
 			AtomLink: AtomLink{
-				Href: "https://claire-blackshaw.com/rss.xml",
+				Href: siteConfig.AbsURL(rel),
 				Rel:  "self",
 				Type: "application/rss+xml",
 			},
-			Description: "Claire Blackshaw's random blog posts on gamedev, roleplaying and various bits n bobs.",
-			Language:    "en-gb",
+			Description: siteConfig.Feed.Description,
+			Language:    siteConfig.Language,
 			Items:       make([]Item, num_posts),
 		},
 	}
 
-	sort.Sort(genData.Feed)
-
 	for i := 0; i < num_posts; i++ {
-		rss.Channel.Items[i] = blogPostToItem(genData.Feed[i])
+		rss.Channel.Items[i] = blogPostToItem(posts[i])
 	}
 
 	// Marshal the RSS data into XML
@@ -147,15 +149,8 @@ func GenerateFeed() error {
 	}
 
 	// Write the XML data to the specified file
-	file, err := os.Create(publicHtmlRoot + "rss.xml")
-	if err != nil {
-		return fmt.Errorf("error creating file: %w", err)
-	}
-	defer file.Close()
-
-	_, err = file.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
-
-	_, err = file.Write(xmlData)
+	buildReport.Output(rel, currentSection, siteConfig.SrcPath("blogdata"))
+	err = writeOutputFile(rel, append([]byte(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"), xmlData...))
 	if err != nil {
 		return fmt.Errorf("error writing to file: %w", err)
 	}
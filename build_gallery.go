@@ -1,10 +1,8 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"html/template"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -28,8 +26,6 @@ type GalleryPost struct {
 var (
 	regUrlSrc     *regexp.Regexp
 	regHeader     *regexp.Regexp
-	galleryTemp   *template.Template
-	galSingleTemp *template.Template
 	gallerySrcDir string
 )
 
@@ -37,17 +33,9 @@ var (
 //
 
 func init() {
-	var err error
-
 	regUrlSrc = regexp.MustCompile(`src="([^"]+)"`)
 	regHeader = regexp.MustCompile(`<h(1|2|3)>([^"]+)</h(1|2|3)>`)
 	gallerySrcDir = filepath.Clean("./gallery")
-
-	galleryTemp, err = template.ParseFiles("Templates/gallery.html")
-	CheckErr(err)
-
-	galSingleTemp, err = template.ParseFiles("Templates/galsingle.html")
-	CheckErr(err)
 }
 
 // //////////////////////////////////////////////////////////////////////////////
@@ -72,9 +60,11 @@ func (gl GalleryListByDate) Swap(i, j int)      { gl[i], gl[j] = gl[j], gl[i] }
 func (gl GalleryListByDate) Less(i, j int) bool { return gl[i].Date.After(gl[j].Date) }
 
 // Support for .png .gif .jpg .mp4 .txt .html
+// Unreadable files go in the report and the walk carries on
 func LoadGalleryFile(path string, info os.FileInfo, err error) error {
 	if err != nil {
-		return err
+		reportError("Gallery", path, err)
+		return nil
 	}
 
 	if info.IsDir() {
@@ -93,29 +83,36 @@ func LoadGalleryFile(path string, info os.FileInfo, err error) error {
 		newPost.PostType = "post"
 
 		relPath, err = filepath.Rel(gallerySrcDir, newPost.File)
+		if err != nil {
+			reportError("Gallery", path, err)
+			return nil
+		}
 		newPost.Link = filepath.ToSlash(strings.TrimSuffix(relPath, filepath.Ext(relPath)) + ".html")
-		CheckErr(err)
 
-		newPost.DateStr = fmt.Sprintf("%d %v %d", newPost.Date.Day(), newPost.Date.Month(), newPost.Date.Year())
+		newPost.DateStr = newPost.Date.Format(dateStrLayout)
 		newPost.Pubdate = newPost.Date.Format(longformPubStr)
 	} else {
-		loadJSONBlob(path+".json", &newPost)
+		if err := loadJSONBlob(path+".json", &newPost); err != nil {
+			reportError("Gallery", path+".json", err)
+			return nil
+		}
 		genData.Gallery = append(genData.Gallery, &newPost)
 		return nil // ALREADY PROCESSED
 	}
 
 	ext := filepath.Ext(path)
+	webFile := "gallery/" + filepath.ToSlash(relPath)
 
 	if (ext == ".gif") || (ext == ".bmp") {
-		newPost.Body = template.HTML(`<img class="pixel" src="` + newPost.File + `">`)
+		newPost.Body = template.HTML(`<img class="pixel" src="` + webFile + `">`)
 		newPost.Include = append(newPost.Include, filepath.ToSlash(relPath))
 		newPost.PostType = "image"
 	} else if (ext == ".png") || (ext == ".jpg") || (ext == ".jpeg") {
-		newPost.Body = template.HTML(`<img src="` + newPost.File + `">`)
+		newPost.Body = template.HTML(`<img src="` + webFile + `">`)
 		newPost.Include = append(newPost.Include, filepath.ToSlash(relPath))
 		newPost.PostType = "image"
 	} else if (ext == ".mp4") || (ext == ".avi") || (ext == ".mov") {
-		newPost.Body = template.HTML(`<video controls><source src="` + newPost.File + `" type="video/mp4"></video>`)
+		newPost.Body = template.HTML(`<video controls><source src="` + webFile + `" type="video/mp4"></video>`)
 		newPost.Include = append(newPost.Include, filepath.ToSlash(relPath))
 		newPost.PostType = "movie"
 	} else if ext == ".txt" {
@@ -123,19 +120,19 @@ func LoadGalleryFile(path string, info os.FileInfo, err error) error {
 
 		body, err := os.ReadFile(path)
 		if err != nil {
-			fmt.Println("Failed to Read: " + path + " - " + err.Error())
-			return err
+			reportError("Gallery", path, err)
+			return nil
 		}
 
 		newPost.Body = template.HTML("<pre>" + string(body) + "</pre>")
-		newPost.Brief = string(body[:128])
+		newPost.Brief = string(body[:min(len(body), 128)])
 
 	} else if ext == ".md" {
 
 		markdown, err := os.ReadFile(path)
 		if err != nil {
-			fmt.Println("Failed to Read: " + path + " - " + err.Error())
-			return err
+			reportError("Gallery", path, err)
+			return nil
 		}
 
 		newPost.Body = template.HTML(regUrlSrc.ReplaceAllStringFunc(string(MarkdownToHTML(markdown)), func(src string) string {
@@ -156,8 +153,8 @@ func LoadGalleryFile(path string, info os.FileInfo, err error) error {
 	} else if ext == ".html" {
 		body, err := os.ReadFile(path)
 		if err != nil {
-			fmt.Println("Failed to Read: " + path + " - " + err.Error())
-			return err
+			reportError("Gallery", path, err)
+			return nil
 		}
 
 		newPost.Body = template.HTML(regUrlSrc.ReplaceAllStringFunc(string(template.HTML(body)), func(src string) string {
@@ -182,15 +179,17 @@ func LoadGalleryFile(path string, info os.FileInfo, err error) error {
 	}
 
 	genData.Gallery = append(genData.Gallery, &newPost)
-	saveJSONBlob(path+".json", &newPost)
+	if dryRunPlan != nil {
+		return nil
+	}
+	if err := saveJSONBlob(path+".json", &newPost); err != nil {
+		reportError("Gallery", path+".json", err)
+	}
 	return nil
 }
 
-func LoadFromGalleryListFolder() {
-	err := filepath.Walk(gallerySrcDir, LoadGalleryFile)
-	if err != nil {
-		log.Println(err)
-	}
+func LoadFromGalleryListFolder() error {
+	return filepath.Walk(gallerySrcDir, LoadGalleryFile)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -200,102 +199,107 @@ func init() {
 
 }
 
-func GenerateGallery() {
+func GenerateGallery() error {
 	sort.Sort(genData.Gallery)
 
 	// Sort out Folders
-	tarDir := filepath.Join(publicHtmlRoot, "gallery")
-	err := os.MkdirAll(tarDir, 0777)
-	CheckErr(err)
+	tarDir := "gallery"
+	err := makeOutputDir(tarDir)
+	if err != nil {
+		return err
+	}
+
+	// Copy Dependent Files - once each as posts can share them
+	var includes []string
+	seen := make(map[string]bool)
+	for _, g := range genData.Gallery {
+		for _, subF := range g.Include {
+			if !seen[subF] {
+				seen[subF] = true
+				includes = append(includes, subF)
+			}
+		}
+	}
+
+	runParallel(len(includes), func(i int) {
+		srcPathInclude := filepath.Join(gallerySrcDir, includes[i])
+		tarPathInclude := filepath.Join(tarDir, includes[i])
+
+		err := copyOutputFile(srcPathInclude, tarPathInclude)
+		if err != nil {
+			reportError("Gallery include", srcPathInclude, err)
+			return
+		}
+		buildManifest.Keep(tarPathInclude, srcPathInclude)
+	})
+
+	// Make Singles
+	runParallel(len(genData.Gallery), func(i int) {
+		g := genData.Gallery[i]
 
-	for i, g := range genData.Gallery {
 		relPath, err := filepath.Rel(gallerySrcDir, g.File)
 		if err != nil {
-			log.Fatalln("Error in File Walk ", err)
+			reportError("Gallery", g.File, err)
+			return
 		}
 		htmlPath := strings.TrimSuffix(relPath, filepath.Ext(relPath)) + ".html"
-		relPath = filepath.Dir(relPath)
-
 		tarPath := filepath.Join(tarDir, htmlPath)
 
-		// Copy Dependent Files
-		for _, subF := range g.Include {
-			srcPathInclude := filepath.Join(gallerySrcDir, subF)
-			tarPathInclude := filepath.Join(tarDir, subF)
-
-			err := os.MkdirAll(filepath.Dir(tarPathInclude), 0777)
-			CheckErr(err)
-
-			_, err = CopyFileLazy(srcPathInclude, tarPathInclude)
-			CheckErr(err)
+		prevLink := "/gallery/"
+		if (i - 1) >= 0 {
+			prevLink = "/gallery/" + genData.Gallery[i-1].Link
+		}
+		nextLink := "/gallery/"
+		if (i + 1) < len(genData.Gallery) {
+			nextLink = "/gallery/" + genData.Gallery[i+1].Link
 		}
 
-		{
-			// Make Single
-			var outFile *os.File
-			outFile, err = os.Create(tarPath)
-			CheckErrContext(err, "Error in File ")
+		buildReport.Output(tarPath, currentSection, g.File)
+		inputs := buildManifest.InputHash(g, prevLink, nextLink)
+		if buildManifest.Fresh(tarPath, inputs) {
+			return
+		}
 
-			prevLink := "/gallery/"
-			if (i - 1) >= 0 {
-				prevLink = "/gallery/" + genData.Gallery[i-1].Link
-			}
-			nextLink := "/gallery/"
-			if (i + 1) < len(genData.Gallery) {
-				nextLink = "/gallery/" + genData.Gallery[i+1].Link
-			}
+		// Write out Frame
+		frameData := &SubPage{
+			Title:   "Gallery: " + g.DateStr,
+			FullURL: "/gallery/" + g.Link,
+		}
 
-			// Make Template
-			var outBuffer bytes.Buffer
-			err = galSingleTemp.Execute(&outBuffer, struct {
-				Post *GalleryPost
-				Prev string
-				Next string
-			}{g, prevLink, nextLink})
-			CheckErrContext(err, "Error in Template ")
-
-			// Write out Frame
-			frameData := &SubPage{
-				Title:   "Gallery: " + g.DateStr,
-				FullURL: "/gallery/" + g.Link,
-				Content: template.HTML(outBuffer.String()),
-			}
+		err = writePage(tarPath, "galsingle.html", struct {
+			Post *GalleryPost
+			Prev string
+			Next string
+		}{g, prevLink, nextLink}, frameData)
+		if err != nil {
+			reportError("Gallery", g.File, err)
+			return
+		}
 
-			err = RootTemp.Execute(outFile, frameData)
-			CheckErrContext(err, "Error in Template ")
+		buildManifest.RecordInputs(tarPath, inputs, g.File)
+	})
 
-			outFile.Close()
-		}
+	genData.ShortGallery = nil
+	genData.ShortGallery = append(genData.ShortGallery, genData.Gallery...)
+	sort.Sort(genData.ShortGallery)
+	if len(genData.ShortGallery) > 8 {
+		genData.ShortGallery = genData.ShortGallery[:8]
 	}
 
 	// Make Index
 	{
-		var outFile *os.File
-		outFile, err = os.Create(publicHtmlRoot + "gallery/index.html")
-		CheckErrContext(err, "Error in File ")
-
-		// Make Template
-		var outBuffer bytes.Buffer
-		err = galleryTemp.Execute(&outBuffer, genData)
-		CheckErrContext(err, "Error in Template ")
-
 		// Write out Frame
 		frameData := &SubPage{
 			Title:   "Gallery Posts",
 			FullURL: "/gallery/",
-			Content: template.HTML(outBuffer.String()),
 		}
 
-		err = RootTemp.Execute(outFile, frameData)
-		CheckErrContext(err, "Error in Template ")
-
-		outFile.Close()
+		buildReport.Output("gallery/index.html", currentSection, gallerySrcDir)
+		err = writePage("gallery/index.html", "gallery.html", genData, frameData)
+		if err != nil {
+			return err
+		}
 	}
 
-	genData.ShortGallery = nil
-	genData.ShortGallery = append(genData.ShortGallery, genData.Gallery...)
-	sort.Sort(genData.ShortGallery)
-	if len(genData.ShortGallery) > 8 {
-		genData.ShortGallery = genData.ShortGallery[:8]
-	}
+	return nil
 }
@@ -1,12 +1,5 @@
 package main
 
-import (
-	"bytes"
-	"html/template"
-	"log"
-	"os"
-)
-
 type HobbyProject struct {
 	Title    string    `json:"title"`
 	Tooltip  string    `json:"tooltip"`
@@ -19,23 +12,15 @@ type HobbyProject struct {
 	Recent   bool      `json:"recent"`
 }
 
-var (
-	hobbyIndexTemp *template.Template
-)
-
-func init() {
-	var err error
-
-	hobbyIndexTemp, err = template.ParseFiles("Templates/projects.html")
-	CheckErr(err)
-}
-
 // //////////////////////////////////////////////////////////////////////////////
 // HobbyList
 type HobbyList []*HobbyProject
 
-func (hl *HobbyList) LoadFromFile() {
-	loadJSONBlob("Data/hobby.js", hl)
+func (hl *HobbyList) LoadFromFile() error {
+	err := loadJSONBlob(siteConfig.SrcPath("Data", "hobby.js"), hl)
+	if err != nil {
+		return err
+	}
 
 	for _, v := range *hl {
 		for _, t := range v.Tags {
@@ -47,40 +32,23 @@ func (hl *HobbyList) LoadFromFile() {
 			}
 		}
 	}
-}
 
-func (hl *HobbyList) GeneratePage() {
-	var err error
-	var outBuffer bytes.Buffer
-
-	err = hobbyIndexTemp.Execute(&outBuffer, hl)
-	CheckErr(err)
+	return nil
+}
 
+func (hl *HobbyList) GeneratePage() error {
 	// Write out Frame
 	frameData := &SubPage{
 		Title:   "Hobby",
 		FullURL: "/hobby/",
-		Content: template.HTML(outBuffer.String()),
 	}
 
-	os.MkdirAll(publicHtmlRoot+"projects/", 0777)
-
-	f, fileErr := os.Create(publicHtmlRoot + "projects/index.html")
-	if fileErr != nil {
-		log.Fatalln("Error in File ", fileErr)
-	}
-
-	err = RootTemp.Execute(f, frameData)
-	CheckErr(err)
-
-	f.Close()
+	buildReport.Output("projects/index.html", currentSection, siteConfig.SrcPath("Data", "hobby.js"))
+	return writePage("projects/index.html", "projects.html", hl, frameData)
 }
 
 // //////////////////////////////////////////////////////////////////////////////
 // Generate Hobby
-func GenerateHobby() {
-	os.RemoveAll(publicHtmlRoot + "hobby/")
-	_ = os.MkdirAll(publicHtmlRoot+"hobby/", 0777)
-
-	genData.Hobby.GeneratePage()
+func GenerateHobby() error {
+	return genData.Hobby.GeneratePage()
 }
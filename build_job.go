@@ -1,9 +1,6 @@
 package main
 
 import (
-	"bytes"
-	"html/template"
-	"os"
 	"sort"
 	"time"
 )
@@ -41,39 +38,55 @@ func (jo JobList) Len() int           { return len(jo) }
 func (jo JobList) Swap(i, j int)      { jo[i], jo[j] = jo[j], jo[i] }
 func (jo JobList) Less(i, j int) bool { return jo[i].Date.After(jo[j].Date) }
 
-func (jo *JobList) LoadFromFile() {
-	loadJSONBlob("Data/job.js", jo)
+func (jo *JobList) LoadFromFile() error {
+	err := loadJSONBlob(siteConfig.SrcPath("Data", "job.js"), jo)
+	if err != nil {
+		return err
+	}
 
-	for _, j := range genData.Job {
+	for _, j := range *jo {
 		sort.Sort(j.Games)
 	}
 
 	sort.Sort(jo)
+	return nil
 }
 
-func (jo *JobList) GeneratePage() {
-	jobIndexTemp, err := template.ParseFiles("Templates/job.html")
-	CheckErr(err)
-
-	var outBuffer bytes.Buffer
-	err = jobIndexTemp.Execute(&outBuffer, genData)
-	CheckErrContext(err, "Error in Template ")
-
+func (jo *JobList) GeneratePage() error {
 	// Write out Frame
 	frameData := &SubPage{
 		Title:   "Games Career",
 		FullURL: "/job/",
-		Content: template.HTML(outBuffer.String()),
 	}
 
-	var outFile *os.File
-	outFile, err = os.Create(publicHtmlRoot + "job/index.html")
-	CheckErrContext(err, "Error in File ")
+	buildReport.Output("job/index.html", currentSection, siteConfig.SrcPath("Data", "job.js"))
+	return writePage("job/index.html", "job.html", genData, frameData)
+}
+
+// Jobs and the games and platforms listed under them
+func LoadJobs() error {
+	err := genData.Job.LoadFromFile()
+	if err != nil {
+		return err
+	}
 
-	err = RootTemp.Execute(outFile, frameData)
-	CheckErrContext(err, "Error in Template ")
+	// Build Game List
+	genData.GameList = BuildFromJobs(&genData.Job)
+
+	// Build Platform List
+	platformMap := make(map[string]int)
+	genData.Platforms = []string{}
+	for _, g := range genData.GameList {
+		for _, p := range g.Platform {
+			_, ok := platformMap[p]
+			if !ok {
+				platformMap[p] = 1
+				genData.Platforms = append(genData.Platforms, p)
+			}
+		}
+	}
 
-	outFile.Close()
+	return nil
 }
 
 // //////////////////////////////////////////////////////////////////////////////
@@ -94,9 +107,6 @@ func BuildFromJobs(jo *JobList) (gl GameList) {
 
 // //////////////////////////////////////////////////////////////////////////////
 // Job Page
-func GenerateJob() {
-	os.RemoveAll(publicHtmlRoot + "job/")
-	_ = os.MkdirAll(publicHtmlRoot+"job/", 0777)
-
-	genData.Job.GeneratePage()
+func GenerateJob() error {
+	return genData.Job.GeneratePage()
 }
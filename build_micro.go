@@ -1,21 +1,15 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
-	"html"
 	"html/template"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
 	"time"
-	"unicode"
-	"unicode/utf8"
 
-	"github.com/microcosm-cc/bluemonday"
 	"github.com/russross/blackfriday"
 )
 
@@ -23,6 +17,7 @@ type MicroPost struct {
 	Title string    `json:"title"`
 	Date  time.Time `json:"pubDate"`
 	Class string    `json:"classname"`
+	Draft bool      `json:"draft,omitempty"`
 
 	Body    template.HTML `json:"-"`
 	DateStr string        `json:"-"`
@@ -37,9 +32,11 @@ func (bl MicroList) Len() int           { return len(bl) }
 func (bl MicroList) Swap(i, j int)      { bl[i], bl[j] = bl[j], bl[i] }
 func (bl MicroList) Less(i, j int) bool { return bl[i].Date.After(bl[j].Date) }
 
+// Unreadable files go in the report and the walk carries on
 func LoadSingleFile(path string, info os.FileInfo, err error) error {
 	if err != nil {
-		return err
+		reportError("Micro", path, err)
+		return nil
 	}
 
 	if info.IsDir() {
@@ -55,8 +52,8 @@ func LoadSingleFile(path string, info os.FileInfo, err error) error {
 	if ext == ".md" {
 		markdown, err := os.ReadFile(path)
 		if err != nil {
-			fmt.Println("Failed to Read: " + path + " - " + err.Error())
-			return err
+			reportError("Micro", path, err)
+			return nil
 		}
 
 		newPost.Body = MarkdownToHTML(markdown)
@@ -64,8 +61,8 @@ func LoadSingleFile(path string, info os.FileInfo, err error) error {
 	} else if ext == ".html" {
 		body, err := os.ReadFile(path)
 		if err != nil {
-			fmt.Println("Failed to Read: " + path + " - " + err.Error())
-			return err
+			reportError("Micro", path, err)
+			return nil
 		}
 		newPost.Body = template.HTML(body)
 	} else if ext == ".json" {
@@ -79,22 +76,32 @@ func LoadSingleFile(path string, info os.FileInfo, err error) error {
 	if _, err := os.Stat(path + ".json"); os.IsNotExist(err) {
 		newPost.Title = title
 		newPost.Date = info.ModTime()
-	} else {
-		loadJSONBlob(path+".json", &newPost)
+	} else if err := loadJSONBlob(path+".json", &newPost); err != nil {
+		reportError("Micro", path+".json", err)
+		return nil
+	}
+
+	if holdBack(path, newPost.Draft, newPost.Date) {
+		return nil
 	}
 
 	newPost.Pubdate = newPost.Date.Format(longformPubStr)
-	newPost.DateStr = fmt.Sprintf("%d %v %d", newPost.Date.Day(), newPost.Date.Month(), newPost.Date.Year())
+	newPost.DateStr = newPost.Date.Format(dateStrLayout)
 	genData.Micro = append(genData.Micro, &newPost)
 
-	saveJSONBlob(path+".json", &newPost)
+	if dryRunPlan != nil {
+		return nil
+	}
+	if err := saveJSONBlob(path+".json", &newPost); err != nil {
+		reportError("Micro", path+".json", err)
+	}
 	return nil
 }
 
-func LoadFromMicroListFolder() {
-	err := filepath.Walk("./microdata", LoadSingleFile)
+func LoadFromMicroListFolder() error {
+	err := filepath.Walk(siteConfig.SrcPath("microdata"), LoadSingleFile)
 	if err != nil {
-		log.Println(err)
+		return err
 	}
 
 	// merge microdata into blog feed
@@ -114,7 +121,9 @@ func LoadFromMicroListFolder() {
 			braw = braw[0:loc[0]] + braw[loc[1]:]
 		}
 		v.Title = strings.Trim(v.Title, " .\n")
-		v.Title = strings.ToUpper(v.Title[0:1]) + v.Title[1:]
+		if len(v.Title) > 0 {
+			v.Title = strings.ToUpper(v.Title[0:1]) + v.Title[1:]
+		}
 
 		// Convert to Blog
 		blogFromMicro := BlogPost{
@@ -124,26 +133,8 @@ func LoadFromMicroListFolder() {
 			Body:  template.HTML(braw),
 		}
 
-		// strip html from body
-		plainBody := braw
-		p := bluemonday.StripTagsPolicy()
-		plainBody = p.Sanitize(plainBody)
-		plainBody = strings.ReplaceAll(plainBody, "\n", "")
-		if len(plainBody) > 400 {
-			plainBody = plainBody[0:400]
-
-			r, size := utf8.DecodeLastRuneInString(plainBody)
-			for !unicode.IsSpace(r) {
-				if r == utf8.RuneError && (size == 0 || size == 1) {
-					size = 0
-				}
-
-				plainBody = plainBody[:len(plainBody)-size]
-				r, size = utf8.DecodeLastRuneInString(plainBody)
-			}
-		}
-		blogFromMicro.ShortDesc = html.UnescapeString(plainBody)
-		blogFromMicro.ShortDesc = strings.ReplaceAll(blogFromMicro.ShortDesc, ".", ". ")
+		blogFromMicro.bodyLoaded()
+		blogFromMicro.ShortDesc = blogFromMicro.summary(400)
 
 		blogFromMicro.RawCategory = []BlogCat{"micro"}
 		blogFromMicro.Category = []BlogCat{"micro"}
@@ -152,6 +143,8 @@ func LoadFromMicroListFolder() {
 		blogFromMicro.Class = v.Class
 		genData.Feed = append(genData.Feed, &blogFromMicro)
 	}
+
+	return nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -183,32 +176,15 @@ func MarkdownToHTML(input []byte) template.HTML {
 	return template.HTML(output)
 }
 
-func GenerateMicro() {
-	microTemp, err := template.ParseFiles("Templates/micro.html")
-	CheckErr(err)
-
+func GenerateMicro() error {
 	sort.Sort(genData.Micro)
 
-	var outBuffer bytes.Buffer
-	err = microTemp.Execute(&outBuffer, genData)
-	CheckErrContext(err, "Error in Template ")
-
 	// Write out Frame
 	frameData := &SubPage{
 		Title:   "Micro Posts",
 		FullURL: "/micro/",
-		Content: template.HTML(outBuffer.String()),
 	}
 
-	err = os.MkdirAll(publicHtmlRoot+"micro", 0777)
-	CheckErr(err)
-
-	var outFile *os.File
-	outFile, err = os.Create(publicHtmlRoot + "micro/index.html")
-	CheckErrContext(err, "Error in File ")
-
-	err = RootTemp.Execute(outFile, frameData)
-	CheckErrContext(err, "Error in Template ")
-
-	outFile.Close()
+	buildReport.Output("micro/index.html", currentSection, siteConfig.SrcPath("microdata"))
+	return writePage("micro/index.html", "micro.html", genData, frameData)
 }
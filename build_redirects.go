@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RedirectLedger - every URL a post has had, kept in the source folder so
+// links survive a post's date changing
+type RedirectLedger struct {
+	Links     map[string]string `json:"links"`     // post key to its URL at the last build
+	Redirects map[string]string `json:"redirects"` // old URL to post key
+}
+
+type Redirect struct {
+	From string
+	To   string
+}
+
+const redirectLedgerFile = "redirects.json"
+
+var redirectStub = template.Must(template.New("redirect").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.To}}</title>
+<link rel="canonical" href="{{.Abs}}">
+<meta name="robots" content="noindex">
+<meta http-equiv="refresh" content="0; url={{.To}}">
+</head>
+<body><a href="{{.To}}">{{.To}}</a></body>
+</html>
+`))
+
+// Site relative with a trailing slash unless it names a file
+func cleanAlias(alias string) string {
+	alias = "/" + strings.TrimLeft(strings.TrimSpace(alias), "/")
+	if !strings.HasSuffix(alias, "/") && path.Ext(alias) == "" {
+		alias += "/"
+	}
+	return alias
+}
+
+func loadRedirectLedger() (*RedirectLedger, error) {
+	ledger := &RedirectLedger{}
+	err := loadJSONBlob(siteConfig.SrcPath("blogdata", redirectLedgerFile), ledger)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if ledger.Links == nil {
+		ledger.Links = make(map[string]string)
+	}
+	if ledger.Redirects == nil {
+		ledger.Redirects = make(map[string]string)
+	}
+	return ledger, nil
+}
+
+// Note any post whose URL changed and work out every redirect, posts that
+// are gone or held back keep their entries for when they come back. Drafts and
+// future posts in a preview build aren't published so stay out of the ledger
+func (l *RedirectLedger) Update(bl BlogList) []Redirect {
+	current := make(map[string]*BlogPost)
+	now := time.Now()
+	for _, bp := range bl {
+		if bp.IsMicro || bp.Link == "" {
+			continue
+		}
+		current[bp.Link] = bp
+
+		if bp.Draft || bp.Date.After(now) {
+			continue
+		}
+		if old, ok := l.Links[bp.Key]; ok && old != bp.Link {
+			l.Redirects[old] = bp.Key
+		}
+		l.Links[bp.Key] = bp.Link
+	}
+
+	targets := make(map[string]string)
+	for from, key := range l.Redirects {
+		if _, ok := current[from]; ok {
+			// A live page has the URL again
+			delete(l.Redirects, from)
+			continue
+		}
+		if bp, ok := current[l.Links[key]]; ok && bp.Key == key {
+			targets[from] = bp.Link
+		}
+	}
+
+	for _, bp := range current {
+		for _, a := range bp.Aliases {
+			from := cleanAlias(a)
+			if _, ok := current[from]; ok {
+				reportWarning(bp.Key, bp.metaFile(), fmt.Errorf("alias %s is the URL of another post, skipped", a))
+				continue
+			}
+			targets[from] = bp.Link
+		}
+	}
+
+	redirects := make([]Redirect, 0, len(targets))
+	for from, to := range targets {
+		redirects = append(redirects, Redirect{from, to})
+	}
+	sort.Slice(redirects, func(i, j int) bool { return redirects[i].From < redirects[j].From })
+	return redirects
+}
+
+// Where the stub page for a redirect goes
+func redirectFile(from string) string {
+	rel := strings.TrimPrefix(from, "/")
+	if strings.HasSuffix(rel, "/") || rel == "" {
+		rel += "index.html"
+	}
+	return rel
+}
+
+// The static copies run alongside the sections, so look at their sources rather
+// than what they have copied so far
+func hasBuiltPage(rel string) bool {
+	if buildReport.Has(rel) {
+		return true
+	}
+
+	sources := []string{siteConfig.SrcPath("static_folder", filepath.FromSlash(rel))}
+	if img, ok := strings.CutPrefix(rel, "images/"); ok {
+		sources = append(sources, siteConfig.SrcPath("images", filepath.FromSlash(img)))
+	}
+	for _, src := range sources {
+		if _, err := os.Stat(src); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Generate Redirects - a stub page at each old URL, plus rules for hosts that read them.
+// Runs after the other sections so a redirect never replaces a page they made
+func GenerateRedirects(bl BlogList) error {
+	ledger, err := loadRedirectLedger()
+	if err != nil {
+		return err
+	}
+
+	before, _ := json.Marshal(ledger)
+	redirects := []Redirect{}
+	for _, r := range ledger.Update(bl) {
+		if hasBuiltPage(redirectFile(r.From)) {
+			reportWarning("Redirect "+r.From, siteConfig.SrcPath("blogdata", redirectLedgerFile), fmt.Errorf("the build already has a page at %s, skipped", r.From))
+			continue
+		}
+		redirects = append(redirects, r)
+	}
+
+	var netlify, htaccess bytes.Buffer
+	for _, r := range redirects {
+		fmt.Fprintf(&netlify, "%s %s 301\n", r.From, r.To)
+		// Redirect would also catch everything under the old URL
+		fmt.Fprintf(&htaccess, "RedirectMatch 301 ^%s$ %s\n", regexp.QuoteMeta(r.From), r.To)
+
+		rel := redirectFile(r.From)
+		var page bytes.Buffer
+		if err := redirectStub.Execute(&page, struct{ To, Abs string }{r.To, siteConfig.AbsURL(r.To)}); err != nil {
+			return err
+		}
+		if err := writeOutputFile(rel, page.Bytes()); err != nil {
+			reportError("Redirect "+r.From, rel, err)
+		}
+	}
+
+	// A copy in static_folder is the site's own and wins
+	for name, rules := range map[string][]byte{"_redirects": netlify.Bytes(), ".htaccess": htaccess.Bytes()} {
+		if len(redirects) == 0 {
+			break
+		}
+		if _, err := os.Stat(siteConfig.SrcPath("static_folder", name)); err == nil {
+			log.Println("Not writing redirect rules over static_folder/" + name)
+			continue
+		}
+		if err := writeOutputFile(name, rules); err != nil {
+			return err
+		}
+	}
+
+	// Only touch the ledger when something moved, serve watches the folder
+	after, _ := json.Marshal(ledger)
+	if dryRunPlan != nil || bytes.Equal(before, after) {
+		return nil
+	}
+	return saveJSONBlob(siteConfig.SrcPath("blogdata", redirectLedgerFile), ledger)
+}
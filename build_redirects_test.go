@@ -0,0 +1,146 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRedirectLedgerUpdate(t *testing.T) {
+	past := time.Date(2020, time.April, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Now().AddDate(1, 0, 0)
+
+	tests := []struct {
+		name      string
+		links     map[string]string
+		redirects map[string]string
+		posts     []*BlogPost
+		want      []Redirect
+		wantLinks map[string]string
+		wantOld   map[string]string // the ledger's redirects after
+		warnings  int
+	}{
+		{
+			name:      "first build",
+			posts:     []*BlogPost{{Key: "a", Link: "/a/", Date: past}},
+			want:      []Redirect{},
+			wantLinks: map[string]string{"a": "/a/"},
+			wantOld:   map[string]string{},
+		},
+		{
+			name:      "moved",
+			links:     map[string]string{"a": "/old/"},
+			posts:     []*BlogPost{{Key: "a", Link: "/new/", Date: past}},
+			want:      []Redirect{{"/old/", "/new/"}},
+			wantLinks: map[string]string{"a": "/new/"},
+			wantOld:   map[string]string{"/old/": "a"},
+		},
+		{
+			name:      "moved again",
+			links:     map[string]string{"a": "/second/"},
+			redirects: map[string]string{"/first/": "a"},
+			posts:     []*BlogPost{{Key: "a", Link: "/third/", Date: past}},
+			want:      []Redirect{{"/first/", "/third/"}, {"/second/", "/third/"}},
+			wantLinks: map[string]string{"a": "/third/"},
+			wantOld:   map[string]string{"/first/": "a", "/second/": "a"},
+		},
+		{
+			name:      "old URL taken by a live post",
+			links:     map[string]string{"a": "/new/"},
+			redirects: map[string]string{"/old/": "a"},
+			posts:     []*BlogPost{{Key: "a", Link: "/new/", Date: past}, {Key: "b", Link: "/old/", Date: past}},
+			want:      []Redirect{},
+			wantLinks: map[string]string{"a": "/new/", "b": "/old/"},
+			wantOld:   map[string]string{},
+		},
+		{
+			name:      "post gone keeps its entries",
+			links:     map[string]string{"a": "/new/"},
+			redirects: map[string]string{"/old/": "a"},
+			want:      []Redirect{},
+			wantLinks: map[string]string{"a": "/new/"},
+			wantOld:   map[string]string{"/old/": "a"},
+		},
+		{
+			name:      "draft preview",
+			links:     map[string]string{"a": "/old/"},
+			posts:     []*BlogPost{{Key: "a", Link: "/new/", Date: past, Draft: true}},
+			want:      []Redirect{},
+			wantLinks: map[string]string{"a": "/old/"},
+			wantOld:   map[string]string{},
+		},
+		{
+			name:      "future preview",
+			posts:     []*BlogPost{{Key: "a", Link: "/soon/", Date: future}},
+			want:      []Redirect{},
+			wantLinks: map[string]string{},
+			wantOld:   map[string]string{},
+		},
+		{
+			name:      "micro posts and posts without a link",
+			posts:     []*BlogPost{{Key: "m", Link: "/micro/m/", Date: past, IsMicro: true}, {Key: "x", Date: past}},
+			want:      []Redirect{},
+			wantLinks: map[string]string{},
+			wantOld:   map[string]string{},
+		},
+		{
+			name:      "aliases",
+			posts:     []*BlogPost{{Key: "a", Link: "/a/", Date: past, Aliases: []string{"old", " /file.html"}}},
+			want:      []Redirect{{"/file.html", "/a/"}, {"/old/", "/a/"}},
+			wantLinks: map[string]string{"a": "/a/"},
+			wantOld:   map[string]string{},
+		},
+		{
+			name:      "alias of another post",
+			posts:     []*BlogPost{{Key: "a", Link: "/a/", Date: past, Aliases: []string{"/b"}}, {Key: "b", Link: "/b/", Date: past}},
+			want:      []Redirect{},
+			wantLinks: map[string]string{"a": "/a/", "b": "/b/"},
+			wantOld:   map[string]string{},
+			warnings:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSite(t)
+			buildErrors.Reset()
+			t.Cleanup(buildErrors.Reset)
+
+			l := &RedirectLedger{Links: map[string]string{}, Redirects: map[string]string{}}
+			for k, v := range tt.links {
+				l.Links[k] = v
+			}
+			for k, v := range tt.redirects {
+				l.Redirects[k] = v
+			}
+
+			got := l.Update(tt.posts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("redirects = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(l.Links, tt.wantLinks) {
+				t.Errorf("links = %v, want %v", l.Links, tt.wantLinks)
+			}
+			if !reflect.DeepEqual(l.Redirects, tt.wantOld) {
+				t.Errorf("ledger redirects = %v, want %v", l.Redirects, tt.wantOld)
+			}
+			if n := buildErrors.Warnings(); n != tt.warnings {
+				t.Errorf("%d warnings, want %d", n, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestRedirectFile(t *testing.T) {
+	for from, want := range map[string]string{
+		"/":             "index.html",
+		"/old/":         "old/index.html",
+		"/blog/a/b/":    "blog/a/b/index.html",
+		"/file.html":    "file.html",
+		"/feed/rss.xml": "feed/rss.xml",
+	} {
+		if got := redirectFile(from); got != want {
+			t.Errorf("redirectFile(%q) = %q, want %q", from, got, want)
+		}
+	}
+}
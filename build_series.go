@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BlogSeries - posts written as parts of one longer piece
+type BlogSeries struct {
+	Name  string
+	URL   string
+	Parts BlogList // in reading order
+}
+
+// SeriesNav - where a post sits in its series
+type SeriesNav struct {
+	*BlogSeries
+	Part int // seriesPart if the post has one, otherwise one after the part before
+	Prev *BlogPost
+	Next *BlogPost
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Series - numbered parts go first by seriesPart, then the rest by date
+func linkSeries(bl BlogList) []*BlogSeries {
+	var list []*BlogSeries
+	byName := make(map[string]*BlogSeries)
+	byURL := make(map[string]*BlogSeries)
+
+	for _, bp := range bl {
+		bp.SeriesNav = nil
+		if bp.Series == "" {
+			continue
+		}
+
+		s, ok := byName[bp.Series]
+		if !ok {
+			s = &BlogSeries{
+				Name: bp.Series,
+				URL:  "/blog/series/" + slugify(bp.Series) + "/",
+			}
+			byName[bp.Series] = s
+
+			// Names that slug the same would share a page
+			if other, ok := byURL[s.URL]; ok {
+				reportError("Series "+s.Name, bp.metaFile(), fmt.Errorf("%s is also the page of series %s", s.URL, other.Name))
+			} else {
+				byURL[s.URL] = s
+				list = append(list, s)
+			}
+		}
+		s.Parts = append(s.Parts, bp)
+	}
+
+	for _, s := range list {
+		sort.SliceStable(s.Parts, func(i, j int) bool {
+			a, b := s.Parts[i], s.Parts[j]
+			if (a.SeriesPart > 0) != (b.SeriesPart > 0) {
+				return a.SeriesPart > 0
+			}
+			if a.SeriesPart != b.SeriesPart {
+				return a.SeriesPart < b.SeriesPart
+			}
+			return a.Date.Before(b.Date)
+		})
+
+		part := 0
+		for i, bp := range s.Parts {
+			part++
+			if bp.SeriesPart > 0 {
+				part = bp.SeriesPart
+			}
+
+			nav := &SeriesNav{BlogSeries: s, Part: part}
+			if i > 0 {
+				nav.Prev = s.Parts[i-1]
+			}
+			if i+1 < len(s.Parts) {
+				nav.Next = s.Parts[i+1]
+			}
+			bp.SeriesNav = nav
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func GenerateBlogSeries(list []*BlogSeries) {
+	runParallel(len(list), func(i int) {
+		s := list[i]
+		outPath := strings.TrimPrefix(s.URL, "/") + "index.html"
+		buildReport.Output(outPath, currentSection, siteConfig.SrcPath("blogdata"))
+
+		inputs := buildManifest.InputHash(s, s.Parts.cardInputs())
+		if buildManifest.Fresh(outPath, inputs) {
+			return
+		}
+
+		// Write out Frame
+		frameData := &SubPage{
+			Title:   "Series - " + s.Name,
+			FullURL: s.URL,
+		}
+
+		if err := writePage(outPath, "series.html", s, frameData); err != nil {
+			reportError("Series "+s.Name, outPath, err)
+			return
+		}
+		buildManifest.RecordInputs(outPath, inputs)
+	})
+}
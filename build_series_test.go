@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A post in a series, day is its day in April 2020
+type seriesPost struct {
+	key    string
+	series string
+	part   int
+	day    int
+}
+
+func TestLinkSeries(t *testing.T) {
+	tests := []struct {
+		name  string
+		posts []seriesPost // in feed order, newest first
+		parts []string     // each series as "<name>: key#part ..."
+	}{
+		{
+			name:  "by date",
+			posts: []seriesPost{{"c", "Engines", 0, 3}, {"a", "Engines", 0, 1}, {"b", "Engines", 0, 2}},
+			parts: []string{"Engines: a#1 b#2 c#3"},
+		},
+		{
+			name:  "numbered before dated",
+			posts: []seriesPost{{"late", "Engines", 0, 9}, {"two", "Engines", 2, 5}, {"early", "Engines", 0, 1}, {"one", "Engines", 1, 7}},
+			parts: []string{"Engines: one#1 two#2 early#3 late#4"},
+		},
+		{
+			name:  "gaps kept",
+			posts: []seriesPost{{"ten", "Engines", 10, 1}, {"three", "Engines", 3, 2}, {"after", "Engines", 0, 3}},
+			parts: []string{"Engines: three#3 ten#10 after#11"},
+		},
+		{
+			name:  "same number by date",
+			posts: []seriesPost{{"b", "Engines", 1, 2}, {"a", "Engines", 1, 1}},
+			parts: []string{"Engines: a#1 b#1"},
+		},
+		{
+			name:  "several series by name",
+			posts: []seriesPost{{"z1", "Zebra", 0, 1}, {"solo", "", 0, 2}, {"a1", "Apple", 0, 3}},
+			parts: []string{"Apple: a1#1", "Zebra: z1#1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bl BlogList
+			for _, p := range tt.posts {
+				bl = append(bl, &BlogPost{
+					Key:        p.key,
+					Series:     p.series,
+					SeriesPart: p.part,
+					Date:       time.Date(2020, time.April, p.day, 0, 0, 0, 0, time.UTC),
+				})
+			}
+
+			var parts []string
+			for _, s := range linkSeries(bl) {
+				line := s.Name + ":"
+				for _, bp := range s.Parts {
+					line += fmt.Sprintf(" %s#%d", bp.Key, bp.SeriesNav.Part)
+				}
+				parts = append(parts, line)
+			}
+			if !reflect.DeepEqual(parts, tt.parts) {
+				t.Errorf("series = %q, want %q", parts, tt.parts)
+			}
+
+			for _, bp := range bl {
+				if (bp.SeriesNav != nil) != (bp.Series != "") {
+					t.Errorf("%s: series nav %v", bp.Key, bp.SeriesNav)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkSeriesNav(t *testing.T) {
+	a := &BlogPost{Key: "a", Series: "Engines", SeriesPart: 1}
+	b := &BlogPost{Key: "b", Series: "Engines", SeriesPart: 2}
+	c := &BlogPost{Key: "c", Series: "Engines", SeriesPart: 3}
+	linkSeries(BlogList{c, b, a})
+
+	tests := []struct {
+		post       *BlogPost
+		prev, next *BlogPost
+	}{
+		{a, nil, b},
+		{b, a, c},
+		{c, b, nil},
+	}
+	for _, tt := range tests {
+		if nav := tt.post.SeriesNav; nav.Prev != tt.prev || nav.Next != tt.next {
+			t.Errorf("%s: prev %v, next %v", tt.post.Key, nav.Prev, nav.Next)
+		}
+	}
+}
+
+func TestLinkSeriesSlugCollision(t *testing.T) {
+	useTestSite(t)
+	buildErrors.Reset()
+	t.Cleanup(buildErrors.Reset)
+
+	list := linkSeries(BlogList{
+		{Key: "a", Series: "Game Dev"},
+		{Key: "b", Series: "game-dev"},
+	})
+	if len(list) != 1 || buildErrors.Count() != 1 {
+		t.Errorf("got %d series and %d errors", len(list), buildErrors.Count())
+	}
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
+	"bytes"
 	"encoding/xml"
-	"log"
-	"os"
+	"fmt"
 	"time"
 )
 
@@ -16,23 +16,18 @@ type SiteMapLink struct {
 }
 
 // //////////////////////////////////////////////////////////////////////////////
-// Site Map
-func GenerateSiteMap() {
-	var siteLinks []SiteMapLink
-
-	siteLinks = append(siteLinks, SiteMapLink{
-		Loc:        "/",
+// Section Links
+func indexSiteMapLink(loc string) SiteMapLink {
+	return SiteMapLink{
+		Loc:        loc,
 		LastMod:    time.Now(),
 		Changefreq: "daily",
 		Priority:   1.0,
-	})
+	}
+}
 
-	siteLinks = append(siteLinks, SiteMapLink{
-		Loc:        "/blog/",
-		LastMod:    time.Now(),
-		Changefreq: "daily",
-		Priority:   1.0,
-	})
+func blogSiteMap() []SiteMapLink {
+	siteLinks := []SiteMapLink{indexSiteMapLink("/blog/")}
 
 	for _, v := range genData.Feed {
 		siteLinks = append(siteLinks, SiteMapLink{
@@ -43,6 +38,12 @@ func GenerateSiteMap() {
 		})
 	}
 
+	return siteLinks
+}
+
+func gallerySiteMap() []SiteMapLink {
+	var siteLinks []SiteMapLink
+
 	for _, g := range genData.Gallery {
 		siteLinks = append(siteLinks, SiteMapLink{
 			Loc:        g.Link,
@@ -52,20 +53,26 @@ func GenerateSiteMap() {
 		})
 	}
 
-	f, err := os.Create(publicHtmlRoot + "sitemap.xml")
-	CheckErrContext(err, "Error in Sitemap ")
+	return siteLinks
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Site Map - built from what every active section contributes
+func GenerateSiteMap() error {
+	siteLinks := activeSiteMap()
 
+	var f bytes.Buffer
 	f.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
   <urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
   `)
 	for _, v := range siteLinks {
 		s, err := xml.MarshalIndent(v, "  ", "  ")
 		if err != nil {
-			log.Fatalln("Problem writing link ", err)
+			return fmt.Errorf("problem writing link %s: %w", v.Loc, err)
 		}
 		f.Write(s)
 	}
 	f.WriteString(`</urlset><!--END-->`)
 
-	f.Close()
+	return writeOutputFile("sitemap.xml", f.Bytes())
 }
@@ -0,0 +1,606 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Exit codes for scripts
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+type command struct {
+	Name  string
+	Usage string
+	Run   func(args []string) int
+}
+
+var commands []*command
+
+var verbose bool
+
+func init() {
+	commands = []*command{
+		{"build", "Generate the whole website", runBuild},
+		{"serve", "Serve the website and admin pages", runServe},
+		{"new", "Create a new post: new [flags] post|micro|gallery <title>", runNew},
+		{"check", "Validate content and data files without writing anything", runCheck},
+		{"migrate", "Move blogData.js entries into front matter in each post", runMigrate},
+		{"clean", "Remove the generated output folder", runClean},
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Common Flags
+type commonFlags struct {
+	ConfigFile string
+	SourceDir  string
+	OutputDir  string
+	Verbose    bool
+	Quiet      bool
+	Workers    int
+}
+
+func newFlagSet(name string) (*flag.FlagSet, *commonFlags) {
+	cf := &commonFlags{}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&cf.ConfigFile, "config", defaultConfigFile, "Site config file")
+	fs.StringVar(&cf.SourceDir, "src", "", "Source folder (overrides config)")
+	fs.StringVar(&cf.OutputDir, "out", "", "Output folder (overrides config)")
+	fs.BoolVar(&cf.Verbose, "v", false, "Verbose logging")
+	fs.BoolVar(&cf.Quiet, "q", false, "Only log errors, which still go to stderr")
+	fs.IntVar(&cf.Workers, "j", 0, "Pages to render at once (overrides config, 0 uses every core)")
+	return fs, cf
+}
+
+func (cf *commonFlags) apply() error {
+	verbose = cf.Verbose
+	if cf.Quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	cfg, err := loadSiteConfig(cf.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	if cf.SourceDir != "" {
+		cfg.SourceDir = filepath.Clean(cf.SourceDir)
+	}
+	if cf.OutputDir != "" {
+		cfg.OutputDir = cf.OutputDir
+	}
+	if cf.Workers > 0 {
+		cfg.Workers = cf.Workers
+	}
+	applySiteConfig(cfg)
+	return nil
+}
+
+// Parse the flags and load the config, reporting the exit code on failure
+func parseCommand(fs *flag.FlagSet, cf *commonFlags, args []string) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		return exitUsage, false
+	}
+
+	if err := cf.apply(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure, false
+	}
+
+	return exitOK, true
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: fpwebtool <command> [flags]")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.Name, c.Usage)
+	}
+	fmt.Fprintln(os.Stderr, "Run 'fpwebtool <command> -h' for command flags.")
+}
+
+func runCommand(args []string) int {
+	// Old style invocation "fpwebtool -gen" keeps the serve + console behaviour
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "-help") {
+		args = append([]string{"serve", "-repl"}, args...)
+	}
+
+	for _, c := range commands {
+		if c.Name == args[0] {
+			return c.Run(args[1:])
+		}
+	}
+
+	printUsage()
+	if args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		return exitOK
+	}
+	return exitUsage
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Build
+func runBuild(args []string) int {
+	fs, cf := newFlagSet("build")
+	fs.BoolVar(&fullBuild, "full", false, "Ignore the build manifest and rebuild everything")
+	fs.BoolVar(&dryRun, "dry-run", false, "List the files a build would create, change or remove without writing anything")
+	publishFlags(fs)
+	if code, ok := parseCommand(fs, cf, args); !ok {
+		return code
+	}
+
+	if err := Generate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Serve
+func runServe(args []string) int {
+	fs, cf := newFlagSet("serve")
+	flagGenSite := fs.Bool("gen", false, "Generate the whole website before serving")
+	flagRepl := fs.Bool("repl", false, "Read commands from the console")
+	flagAddr := fs.String("addr", "", "Listen address (overrides config)")
+	flagWatch := fs.Bool("watch", false, "Regenerate and reload open pages when source files change")
+	flagPoll := fs.Duration("poll", time.Second, "How often -watch checks for changes")
+	publishFlags(fs)
+	if code, ok := parseCommand(fs, cf, args); !ok {
+		return code
+	}
+
+	if *flagAddr != "" {
+		siteConfig.ListenAddr = *flagAddr
+	}
+	if err := recoverOutput(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	// Failures are reported but the server still comes up
+	if *flagGenSite {
+		if err := Generate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	} else if err := serveDataOnly(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	wf := MakeWebFace(siteConfig.ListenAddr, publicHtmlRoot, *flagWatch)
+
+	var lines chan string
+	if *flagRepl {
+		lines = scanForInput()
+	}
+
+	var changes chan map[string]bool
+	if *flagWatch {
+		changes = MakeWatcher(*flagPoll).Changes
+	}
+
+	due := time.NewTimer(0)
+	scheduleRebuild(due)
+
+	for {
+		if *flagRepl {
+			fmt.Println("Enter Command: ")
+		}
+		select {
+		case line := <-lines:
+			processCommand(line, wf)
+		case m := <-wf.InMsg:
+			processCommand(m, wf)
+		case changed := <-changes:
+			regenerateFor(changed)
+			wf.Reload()
+		case <-due.C:
+			log.Println("Scheduled post is due, regenerating")
+			if err := Generate(); err != nil {
+				errorLog.Println(err)
+			}
+			wf.Reload()
+		}
+		scheduleRebuild(due)
+	}
+}
+
+func publishFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&buildDrafts, "drafts", false, "Include draft posts")
+	fs.BoolVar(&buildFuture, "future", false, "Include posts dated in the future")
+}
+
+// Set the timer for the next scheduled post, if there is one
+func scheduleRebuild(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+
+	if genData != nil && !genData.NextDue.IsZero() {
+		log.Println("Next scheduled post is due", genData.NextDue)
+		t.Reset(time.Until(genData.NextDue) + time.Second)
+	}
+}
+
+// Load everything the admin pages need but only build the serve sections
+func serveDataOnly() error {
+	buildErrors.Reset()
+
+	sections, err := enabledSections()
+	if err != nil {
+		return err
+	}
+	serving, err := serveSections()
+	if err != nil {
+		return err
+	}
+
+	resetTemplates()
+	generateDataOnly(sections)
+	for _, s := range serving {
+		generateSection(s.Name(), s.Generate)
+	}
+
+	buildErrors.Print(os.Stderr)
+	return nil
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// New
+var regSlugChar = regexp.MustCompile("[^a-z0-9]+")
+
+func slugify(s string) string {
+	return strings.Trim(regSlugChar.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+func runNew(args []string) int {
+	fs, cf := newFlagSet("new")
+	flagKey := fs.String("key", "", "File name / key to use instead of one made from the title")
+	flagHTML := fs.Bool("html", false, "Write a blog post body as html instead of markdown")
+	flagFormat := fs.String("format", "yaml", "Blog post front matter: "+strings.Join(frontMatterFormats, ", "))
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: fpwebtool new [flags] post|micro|gallery <title>")
+		fs.PrintDefaults()
+	}
+
+	if code, ok := parseCommand(fs, cf, args); !ok {
+		return code
+	}
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return exitUsage
+	}
+
+	kind := fs.Arg(0)
+	title := strings.Join(fs.Args()[1:], " ")
+	key := *flagKey
+	if key == "" {
+		key = slugify(title)
+	}
+	if key == "" {
+		fmt.Fprintln(os.Stderr, "Unable to make a key from title:", title)
+		return exitUsage
+	}
+
+	var path string
+	var err error
+	switch kind {
+	case "post":
+		path, err = newBlogPost(key, title, !*flagHTML, *flagFormat)
+	case "micro":
+		path, err = newContentFile(siteConfig.SrcPath("microdata", key+".md"), "# "+title+"\n\n")
+	case "gallery":
+		path, err = newContentFile(siteConfig.SrcPath("gallery", key+".md"), "# "+title+"\n\n")
+	default:
+		fs.Usage()
+		return exitUsage
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	fmt.Println(path)
+	return exitOK
+}
+
+func newContentFile(path string, body string) (string, error) {
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("%s already exists", path)
+	}
+
+	err := os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return "", err
+	}
+
+	return path, os.WriteFile(path, []byte(body), 0666)
+}
+
+// Posts carry their details as front matter, blogData.js is left alone
+func newBlogPost(key string, title string, markdown bool, format string) (string, error) {
+	var bl BlogList
+	if err := bl.LoadFromFile(); err != nil {
+		return "", err
+	}
+
+	if bl.Get(key) != nil {
+		return "", fmt.Errorf("post %s already exists", key)
+	}
+
+	bp := &BlogPost{
+		Key:         key,
+		Title:       title,
+		RawCategory: []BlogCat{},
+	}
+	bp.SetNewPubDate(time.Now().UTC().Truncate(time.Second))
+
+	fm, err := bp.formatFrontMatter(format)
+	if err != nil {
+		return "", err
+	}
+
+	path := siteConfig.SrcPath("blogdata", "post", fmt.Sprintf("%d", bp.Date.Year()), key)
+	body := "<p>" + template.HTMLEscapeString(title) + "</p>\n"
+	if markdown {
+		path, body = path+".md", title+"\n"
+	} else {
+		path += ".html"
+	}
+
+	return newContentFile(path, string(fm)+body)
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Migrate
+func runMigrate(args []string) int {
+	fs, cf := newFlagSet("migrate")
+	flagFormat := fs.String("format", "yaml", "Front matter to write: "+strings.Join(frontMatterFormats, ", "))
+	if code, ok := parseCommand(fs, cf, args); !ok {
+		return code
+	}
+
+	if _, err := new(BlogPost).formatFrontMatter(*flagFormat); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	migrated, left, err := migrateBlogData(*flagFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	fmt.Printf("%d post(s) moved to front matter\n", migrated)
+	if left > 0 {
+		fmt.Printf("%d post(s) left in blogData.js\n", left)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// Write each blogData.js entry to the top of its body file. The old index is kept as
+// blogData.js.bak (.bak.2 and on for later runs), anything that couldn't be moved
+// stays in a new blogData.js
+func migrateBlogData(format string) (int, int, error) {
+	indexFile := siteConfig.SrcPath("blogdata", "blogData.js")
+
+	var bl BlogList
+	if err := loadJSONBlob(indexFile, &bl); err != nil {
+		return 0, 0, err
+	}
+
+	left := BlogList{}
+	for _, bp := range bl {
+		if err := migratePost(bp, format); err != nil {
+			fmt.Fprintln(os.Stderr, bp.Key+":", err)
+			left = append(left, bp)
+		}
+	}
+
+	if err := os.Rename(indexFile, backupName(indexFile)); err != nil {
+		return 0, 0, err
+	}
+	if len(left) > 0 {
+		if err := saveJSONBlob(indexFile, &left); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return len(bl) - len(left), len(left), nil
+}
+
+// First of file.bak, file.bak.2, file.bak.3... that doesn't exist yet
+func backupName(path string) string {
+	name := path + ".bak"
+	for n := 2; ; n++ {
+		if _, err := os.Stat(name); os.IsNotExist(err) {
+			return name
+		}
+		name = fmt.Sprintf("%s.bak.%d", path, n)
+	}
+}
+
+func migratePost(bp *BlogPost, format string) error {
+	if err := bp.FixupDateFromPubStr(); err != nil {
+		return err
+	}
+
+	path := bp.bodyFile()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if fm, _, err := splitFrontMatter(data); err != nil || fm != nil {
+		return fmt.Errorf("%s already starts with front matter", path)
+	}
+
+	fm, err := bp.formatFrontMatter(format)
+	if err != nil {
+		return err
+	}
+
+	log.Println("Migrated", path)
+	return os.WriteFile(path, append(fm, data...), 0666)
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Check
+func checkJSONFile(filename string, jObj interface{}) error {
+	jsonBlob, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(jsonBlob, jObj)
+}
+
+func checkContent() []error {
+	var problems []error
+	fail := func(context string, err error) {
+		problems = append(problems, fmt.Errorf("%s: %w", context, err))
+	}
+
+	var jl JobList
+	if err := checkJSONFile(siteConfig.SrcPath("Data", "job.js"), &jl); err != nil {
+		fail("Data/job.js", err)
+	}
+
+	var hl HobbyList
+	if err := checkJSONFile(siteConfig.SrcPath("Data", "hobby.js"), &hl); err != nil {
+		fail("Data/hobby.js", err)
+	}
+
+	var bl BlogList
+	if err := checkJSONFile(siteConfig.SrcPath("blogdata", "blogData.js"), &bl); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fail("blogdata/blogData.js", err)
+	}
+	bl.mergeFrontMatter(fail)
+
+	var ledger RedirectLedger
+	if err := checkJSONFile(siteConfig.SrcPath("blogdata", redirectLedgerFile), &ledger); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fail("blogdata/"+redirectLedgerFile, err)
+	}
+
+	keys := make(map[string]bool)
+	for _, bp := range bl {
+		if keys[bp.Key] {
+			fail(bp.Key, errors.New("duplicate key"))
+		}
+		keys[bp.Key] = true
+
+		if err := bp.FixupDateFromPubStr(); err != nil {
+			fail(bp.Key, err)
+			continue
+		}
+
+		if _, err := os.Stat(bp.bodyFile()); err != nil {
+			fail(bp.Key, err)
+		}
+
+		if len(bp.BannerImage) > 3 {
+			if _, _, err := getImageDimension(siteConfig.SrcPath(bp.BannerImage)); err != nil {
+				fail(bp.Key+" banner", err)
+			}
+		}
+		if len(bp.SmallImage) > 3 {
+			if _, err := os.Stat(siteConfig.SrcPath(bp.SmallImage)); err != nil {
+				fail(bp.Key+" image", err)
+			}
+		}
+	}
+
+	// Meta data written next to micro and gallery files
+	for _, folder := range []string{"microdata", "gallery"} {
+		filepath.Walk(siteConfig.SrcPath(folder), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				fail(folder, err)
+				return nil
+			}
+			if !info.IsDir() && filepath.Ext(path) == ".json" {
+				var meta map[string]interface{}
+				if err := checkJSONFile(path, &meta); err != nil {
+					fail(path, err)
+				}
+			}
+			return nil
+		})
+	}
+
+	return problems
+}
+
+func runCheck(args []string) int {
+	fs, cf := newFlagSet("check")
+	if code, ok := parseCommand(fs, cf, args); !ok {
+		return code
+	}
+
+	problems := checkContent()
+	if len(problems) == 0 {
+		fmt.Println("No problems found")
+		return exitOK
+	}
+
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Error() < problems[j].Error() })
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	fmt.Printf("%d problem(s) found\n", len(problems))
+	return exitFailure
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Clean
+func runClean(args []string) int {
+	fs, cf := newFlagSet("clean")
+	if code, ok := parseCommand(fs, cf, args); !ok {
+		return code
+	}
+
+	outDir, err := filepath.Abs(publicHtmlRoot)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	srcDir, err := filepath.Abs(siteConfig.SourceDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+
+	if outDir == srcDir || outDir == filepath.Dir(outDir) || strings.HasPrefix(srcDir, outDir+string(filepath.Separator)) {
+		fmt.Fprintln(os.Stderr, "Refusing to remove", outDir)
+		return exitFailure
+	}
+
+	for _, dir := range []string{outDir, stagingRoot(), rollbackRoot()} {
+		err = os.RemoveAll(dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+		log.Println("Removed", dir)
+	}
+	return exitOK
+}
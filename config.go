@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type SocialConfig struct {
+	Twitter string `json:"twitter,omitempty"`
+}
+
+type FeedConfig struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Image       string `json:"image,omitempty"`
+}
+
+// PageConfig - a single template page added as a section
+type PageConfig struct {
+	Name     string `json:"name"`
+	Title    string `json:"title"`
+	Template string `json:"template"`
+	Output   string `json:"output"`
+}
+
+type SiteConfig struct {
+	BaseURL      string       `json:"baseURL"`
+	Title        string       `json:"title"`
+	Author       string       `json:"author"`
+	Language     string       `json:"language"`
+	DefaultImage string       `json:"defaultImage"`
+	Social       SocialConfig `json:"social"`
+	Feed         FeedConfig   `json:"feed"`
+
+	ListenAddr string `json:"listenAddr"`
+	Workers    int    `json:"workers,omitempty"` // 0 uses every core
+	SourceDir  string `json:"sourceDir"`
+	OutputDir  string `json:"outputDir"`
+
+	TemplateDir string `json:"templateDir"` // site overrides
+	ThemesDir   string `json:"themesDir"`
+	Theme       string `json:"theme,omitempty"` // empty uses the built in templates
+
+	PageSize int `json:"pageSize,omitempty"` // posts per blog index page, 0 puts them all on one
+
+	Sections      []string     `json:"sections,omitempty"`      // build order, empty uses the defaults
+	ServeSections []string     `json:"serveSections,omitempty"` // built by serve without -gen
+	Pages         []PageConfig `json:"pages,omitempty"`
+}
+
+var (
+	siteConfig     *SiteConfig
+	publicHtmlRoot string
+)
+
+const defaultConfigFile = "site.json"
+
+func defaultSiteConfig() *SiteConfig {
+	return &SiteConfig{
+		Language:     "en",
+		DefaultImage: "/images/fp_twitter_tiny.png",
+		ListenAddr:   ":1667",
+		SourceDir:    ".",
+		OutputDir:    "./public_html/",
+		TemplateDir:  "Templates",
+		ThemesDir:    "themes",
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Load Config - a missing file just leaves the defaults in place
+func loadSiteConfig(filename string) (*SiteConfig, error) {
+	cfg := defaultSiteConfig()
+
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		log.Println("No config found at", filename, "using defaults")
+	} else if err := loadJSONBlob(filename, cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.SourceDir = filepath.Clean(cfg.SourceDir)
+	return cfg, nil
+}
+
+func applySiteConfig(cfg *SiteConfig) {
+	siteConfig = cfg
+	publicHtmlRoot = filepath.ToSlash(filepath.Clean(cfg.OutputDir)) + "/"
+	gallerySrcDir = cfg.SrcPath("gallery")
+}
+
+// SrcPath - path of a source file or folder relative to the source dir
+func (sc *SiteConfig) SrcPath(elem ...string) string {
+	return filepath.Join(append([]string{sc.SourceDir}, elem...)...)
+}
+
+// SrcDirPath - a configured folder, relative ones are under the source dir
+func (sc *SiteConfig) SrcDirPath(dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return sc.SrcPath(dir)
+}
+
+// AbsURL - prefix a site relative path with the base URL
+func (sc *SiteConfig) AbsURL(path string) string {
+	return strings.TrimRight(sc.BaseURL, "/") + "/" + strings.TrimLeft(path, "/")
+}
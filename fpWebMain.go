@@ -3,19 +3,18 @@ package main
 
 import (
 	"bufio"
-	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 )
 
 var (
 	buildDate string
 )
 
-const publicHtmlRoot = "./public_html/"
-
 func scanForInput() chan string {
 	lines := make(chan string)
 
@@ -35,66 +34,224 @@ func processCommand(line string, wf *WebFace) {
 
 	switch line {
 	case "x", "exit":
-		log.Fatalln("Exit")
+		log.Println("Exit")
+		os.Exit(exitOK)
 	case "g", "generate":
-		Generate()
-		wf.GlobalTemplateData["isGenerating"] = "Done"
+		if err := Generate(); err != nil {
+			errorLog.Println(err)
+			wf.GlobalTemplateData["isGenerating"] = err.Error()
+		} else {
+			wf.GlobalTemplateData["isGenerating"] = "Done"
+		}
+		wf.Reload()
 	default:
 		fmt.Println("Commands: " + strings.Join([]string{"g", "generate", "x", "exit"}, " "))
 	}
 }
 
 func copyFolderOver(folder string, destFolder string, c chan (int)) {
-	err := CopyTree("./"+folder, publicHtmlRoot+destFolder, false)
+	srcFolder := siteConfig.SrcPath(folder)
+	section := "copy " + folder
+	start := time.Now()
+
+	var err error
+	if dryRunPlan == nil {
+		err = CopyTree(srcFolder, publicHtmlRoot+destFolder, verbose)
+	}
+	if err != nil {
+		reportError("Copy "+folder, srcFolder, err)
+	}
 
-	CheckErrContext(err, "Failed to copy", folder, publicHtmlRoot+destFolder)
+	// Copied files belong to this build
+	filepath.Walk(srcFolder, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(srcFolder, path)
+			if dryRunPlan != nil {
+				if err := dryRunPlan.Copy(path, filepath.Join(destFolder, rel)); err != nil {
+					reportError("Copy "+folder, path, err)
+				}
+			} else {
+				buildManifest.Keep(filepath.Join(destFolder, rel), path)
+				buildReport.Output(filepath.Join(destFolder, rel), section, path)
+			}
+		}
+		return nil
+	})
 
 	log.Printf("Copied %s to web root\n", folder)
+	buildReport.Time(section, start)
 	c <- 1
 }
 
-func Generate() {
-	os.RemoveAll(publicHtmlRoot)
+// Staging and rollback folders sit next to the output folder so renames stay on one disk
+func stagingRoot() string  { return filepath.Clean(siteConfig.OutputDir) + ".staging" }
+func rollbackRoot() string { return filepath.Clean(siteConfig.OutputDir) + ".prev" }
+
+// Build the whole site into a staging folder, failures are reported at the end
+// rather than stopping the build and the live output is only replaced on success
+func Generate() error {
+	if dryRun {
+		return generateDryRun()
+	}
+
+	buildErrors.Reset()
+	buildReport = newBuildReport()
+	defer func() { buildReport = nil }()
+
+	if err := recoverOutput(); err != nil {
+		return err
+	}
+
+	liveRoot := publicHtmlRoot
+	defer func() { publicHtmlRoot = liveRoot }()
+
+	// Start staging from the live output so incremental builds only touch what changed
+	staging := stagingRoot()
+	err := os.RemoveAll(staging)
+	if err != nil {
+		return err
+	}
+
+	if fullBuild {
+		buildManifest = newBuildManifest()
+	} else {
+		buildManifest = loadBuildManifest()
+		err = LinkTree(liveRoot, staging)
+		if err != nil && !os.IsNotExist(err) {
+			buildManifest = nil
+			return err
+		}
+	}
+
+	publicHtmlRoot = filepath.ToSlash(staging) + "/"
+	err = makeOutputDir("")
+	if err != nil {
+		buildManifest = nil
+		return err
+	}
+
 	c1 := make(chan int)
 	c2 := make(chan int)
 	go copyFolderOver("static_folder", "", c1)
 	go copyFolderOver("images", "images", c2)
 
-	genWebsite()
+	err = genWebsite()
+	if err != nil {
+		reportError("Build", "", err)
+	}
 
 	// wait on gen
 	log.Println("----------------------------------------------\n Waiting on file copies...")
 	<-c1
 	<-c2
+
+	failed := buildErrors.Count()
+	if failed == 0 {
+		buildManifest.Prune()
+	}
+
+	buildReport.Finish(publicHtmlRoot, buildManifest)
+	if err := buildReport.Save(); err != nil {
+		errorLog.Println("Unable to save build report", err)
+	}
+
+	// Leave the live output and its manifest alone if anything failed
+	if failed > 0 {
+		buildManifest = nil
+		buildErrors.Print(os.Stderr)
+		log.Println("Live output left untouched, failed build is in", staging)
+		return fmt.Errorf("build failed with %d error(s)", failed)
+	}
+
+	buildErrors.Print(os.Stderr)
+	err = swapOutput(staging, filepath.Clean(liveRoot))
+	if err != nil {
+		buildManifest = nil
+		return err
+	}
+
+	err = buildManifest.Save()
+	buildManifest = nil
+	return err
 }
 
-func main() {
-	flagGenSite := flag.Bool("gen", false, "Should Website be generated")
-	flag.Parse()
+// Run the build against the live output without writing anything and list what would change
+func generateDryRun() error {
+	buildErrors.Reset()
+	buildManifest = nil
+	dryRunPlan = newDryRunPlan()
+	defer func() { dryRunPlan = nil }()
 
-	log.Println(buildDate)
+	// Kept in memory only, the redirects look up what the build made
+	buildReport = newBuildReport()
+	defer func() { buildReport = nil }()
 
-	if *flagGenSite {
-		Generate()
-	} else {
-		setupRoot()
-		generateDataOnly() // only
-		GenerateGallery()
-		GenerateMicro()
-		GenerateFeed()
-	}
-
-	wf := MakeWebFace(":1667", publicHtmlRoot)
-	lines := scanForInput()
-
-	for {
-		fmt.Println("Enter Command: ")
-		select {
-		case line := <-lines:
-			processCommand(line, wf)
-		case m := <-wf.InMsg:
-			processCommand(m, wf)
-		}
+	c1 := make(chan int)
+	c2 := make(chan int)
+	go copyFolderOver("static_folder", "", c1)
+	go copyFolderOver("images", "images", c2)
+
+	err := genWebsite()
+	if err != nil {
+		reportError("Build", "", err)
+	}
+	<-c1
+	<-c2
+
+	dryRunPlan.FindRemoved()
+	dryRunPlan.Print(os.Stdout)
+
+	buildErrors.Print(os.Stderr)
+	if n := buildErrors.Count(); n > 0 {
+		return fmt.Errorf("build failed with %d error(s)", n)
+	}
+	return nil
+}
 
+// Move the live output to the rollback folder and the staging folder into its place.
+// Two renames, so for a moment there is no live folder and serve answers 404s. A crash
+// in between leaves only the rollback folder, which recoverOutput puts back
+func swapOutput(staging string, live string) error {
+	rollback := rollbackRoot()
+	err := os.RemoveAll(rollback)
+	if err != nil {
+		return err
 	}
+
+	err = os.Rename(live, rollback)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	err = os.Rename(staging, live)
+	if err != nil {
+		// Put the old site back
+		os.Rename(rollback, live)
+		return err
+	}
+
+	log.Println("Swapped new build into", live, "previous build kept in", rollback)
+	return nil
+}
+
+// Put the rollback folder back as the live output if a swap didn't finish
+func recoverOutput() error {
+	live := filepath.Clean(siteConfig.OutputDir)
+	if _, err := os.Stat(live); !os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Stat(rollbackRoot()); err != nil {
+		return nil
+	}
+
+	log.Println("Output folder missing, restoring", rollbackRoot())
+	return os.Rename(rollbackRoot(), live)
+}
+
+func main() {
+	if buildDate != "" {
+		log.Println(buildDate)
+	}
+
+	os.Exit(runCommand(os.Args[1:]))
 }
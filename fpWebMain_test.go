@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSwapOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		live     map[string]string // nil for no live output yet
+		rollback map[string]string // left over from an older swap
+		wantPrev []string
+	}{
+		{
+			name:     "first build",
+			wantPrev: nil,
+		},
+		{
+			name:     "previous build kept",
+			live:     map[string]string{"index.html": "old", "blog/a.html": "old"},
+			wantPrev: []string{"blog/a.html", "index.html"},
+		},
+		{
+			name:     "older rollback replaced",
+			live:     map[string]string{"index.html": "old"},
+			rollback: map[string]string{"ancient.html": "older"},
+			wantPrev: []string{"index.html"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSite(t)
+			live := filepath.Clean(siteConfig.OutputDir)
+			os.RemoveAll(live)
+			if tt.live != nil {
+				writeTestFiles(t, live, tt.live)
+			}
+			if tt.rollback != nil {
+				writeTestFiles(t, rollbackRoot(), tt.rollback)
+			}
+			writeTestFiles(t, stagingRoot(), map[string]string{"index.html": "new"})
+
+			if err := swapOutput(stagingRoot(), live); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := listTestFiles(t, live); !reflect.DeepEqual(got, []string{"index.html"}) {
+				t.Errorf("live = %v", got)
+			}
+			if data, _ := os.ReadFile(filepath.Join(live, "index.html")); string(data) != "new" {
+				t.Errorf("live index = %q, want the staged build", data)
+			}
+			if got := listTestFiles(t, rollbackRoot()); !reflect.DeepEqual(got, tt.wantPrev) {
+				t.Errorf("rollback = %v, want %v", got, tt.wantPrev)
+			}
+			if _, err := os.Stat(stagingRoot()); !os.IsNotExist(err) {
+				t.Error("staging folder still there")
+			}
+		})
+	}
+}
+
+func TestRecoverOutput(t *testing.T) {
+	tests := []struct {
+		name     string
+		live     bool
+		rollback bool
+		wantLive string // content of index.html after, "" for no live output
+		wantPrev bool
+	}{
+		{"interrupted swap restored", false, true, "prev", false},
+		{"live output left alone", true, true, "live", true},
+		{"nothing to restore", false, false, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSite(t)
+			live := filepath.Clean(siteConfig.OutputDir)
+			os.RemoveAll(live)
+			if tt.live {
+				writeTestFiles(t, live, map[string]string{"index.html": "live"})
+			}
+			if tt.rollback {
+				writeTestFiles(t, rollbackRoot(), map[string]string{"index.html": "prev"})
+			}
+
+			if err := recoverOutput(); err != nil {
+				t.Fatal(err)
+			}
+
+			data, _ := os.ReadFile(filepath.Join(live, "index.html"))
+			if string(data) != tt.wantLive {
+				t.Errorf("live index = %q, want %q", data, tt.wantLive)
+			}
+			if _, err := os.Stat(rollbackRoot()); (err == nil) != tt.wantPrev {
+				t.Errorf("rollback kept = %v, want %v", err == nil, tt.wantPrev)
+			}
+		})
+	}
+}
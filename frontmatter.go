@@ -0,0 +1,497 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// //////////////////////////////////////////////////////////////////////////////
+// Front Matter - post details at the top of the body file
+//
+//	---            +++            {
+//	title: Hello   title = "Hi"     "title": "Hi"
+//	---            +++            }
+//
+// Only what posts need is understood: strings, booleans and lists of strings.
+
+var frontMatterFormats = []string{"yaml", "toml", "json"}
+
+// Split a body file into its front matter and the rest, fm is nil if there is none
+func splitFrontMatter(data []byte) (map[string]interface{}, []byte, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	switch {
+	case bytes.HasPrefix(data, []byte("---\n")) || bytes.HasPrefix(data, []byte("---\r\n")):
+		head, body, err := cutFrontMatter(data, "---")
+		if err != nil {
+			return nil, nil, err
+		}
+		fm, err := parseYAMLFrontMatter(head)
+		return fm, body, err
+
+	case bytes.HasPrefix(data, []byte("+++\n")) || bytes.HasPrefix(data, []byte("+++\r\n")):
+		head, body, err := cutFrontMatter(data, "+++")
+		if err != nil {
+			return nil, nil, err
+		}
+		fm, err := parseTOMLFrontMatter(head)
+		return fm, body, err
+
+	case bytes.HasPrefix(data, []byte("{")):
+		fm := make(map[string]interface{})
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if err := dec.Decode(&fm); err != nil {
+			return nil, nil, fmt.Errorf("json front matter: %w", err)
+		}
+		return fm, bytes.TrimLeft(data[dec.InputOffset():], "\r\n"), nil
+	}
+
+	return nil, data, nil
+}
+
+// Which of frontMatterFormats a body file starts with
+func frontMatterFormat(data []byte) string {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	switch {
+	case bytes.HasPrefix(data, []byte("+++")):
+		return "toml"
+	case bytes.HasPrefix(data, []byte("{")):
+		return "json"
+	}
+	return "yaml"
+}
+
+// Lines between the opening and closing fence, and what follows
+func cutFrontMatter(data []byte, fence string) ([]string, []byte, error) {
+	var head []string
+
+	rest := data[bytes.IndexByte(data, '\n')+1:]
+	for len(rest) > 0 {
+		line, next, _ := bytes.Cut(rest, []byte("\n"))
+		line = bytes.TrimRight(line, "\r")
+		rest = next
+
+		if string(line) == fence {
+			return head, rest, nil
+		}
+		head = append(head, string(line))
+	}
+
+	return nil, nil, fmt.Errorf("front matter has no closing %s", fence)
+}
+
+func stripComment(line string) string {
+	inQuote := rune(0)
+	for i, r := range line {
+		switch {
+		case inQuote != 0 && r == inQuote:
+			inQuote = 0
+		case inQuote == 0 && (r == '"' || r == '\''):
+			inQuote = r
+		case inQuote == 0 && r == '#':
+			return strings.TrimSpace(line[:i])
+		}
+	}
+	return strings.TrimSpace(line)
+}
+
+func parseScalar(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case strings.HasPrefix(s, `"`):
+		return strconv.Unquote(s)
+	case strings.HasPrefix(s, `'`) && strings.HasSuffix(s, `'`) && len(s) > 1:
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case strings.HasPrefix(s, "["):
+		return parseInlineList(s)
+	case s == "true":
+		return true, nil
+	case s == "false":
+		return false, nil
+	}
+	return s, nil
+}
+
+// [a, "b, c", 'd']
+func parseInlineList(s string) ([]interface{}, error) {
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("unclosed list %s", s)
+	}
+
+	var items []interface{}
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	start, inQuote := 0, rune(0)
+	for i, r := range inner + "," {
+		switch {
+		case inQuote != 0 && r == inQuote && (i == 0 || inner[i-1] != '\\'):
+			inQuote = 0
+		case inQuote == 0 && (r == '"' || r == '\''):
+			inQuote = r
+		case inQuote == 0 && r == ',':
+			item := strings.TrimSpace(inner[start:min(i, len(inner))])
+			start = i + 1
+			if item == "" {
+				continue
+			}
+			v, err := parseScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+	}
+	return items, nil
+}
+
+// key: value, key: [a, b] and block lists of "- item"
+func parseYAMLFrontMatter(lines []string) (map[string]interface{}, error) {
+	fm := make(map[string]interface{})
+	listKey := ""
+
+	for n, raw := range lines {
+		line := stripComment(raw)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			if listKey == "" {
+				return nil, fmt.Errorf("yaml front matter line %d: list item without a key", n+2)
+			}
+			v, err := parseScalar(strings.TrimPrefix(line, "-"))
+			if err != nil {
+				return nil, fmt.Errorf("yaml front matter line %d: %w", n+2, err)
+			}
+			list, _ := fm[listKey].([]interface{})
+			fm[listKey] = append(list, v)
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("yaml front matter line %d: expected key: value", n+2)
+		}
+		key = strings.TrimSpace(key)
+
+		// Empty unless "- item" lines follow
+		if strings.TrimSpace(value) == "" {
+			listKey = key
+			fm[key] = ""
+			continue
+		}
+
+		listKey = ""
+		v, err := parseScalar(value)
+		if err != nil {
+			return nil, fmt.Errorf("yaml front matter line %d: %w", n+2, err)
+		}
+		fm[key] = v
+	}
+
+	return fm, nil
+}
+
+// key = value, no tables
+func parseTOMLFrontMatter(lines []string) (map[string]interface{}, error) {
+	fm := make(map[string]interface{})
+
+	for n, raw := range lines {
+		line := stripComment(raw)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("toml front matter line %d: tables are not supported", n+2)
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("toml front matter line %d: expected key = value", n+2)
+		}
+
+		v, err := parseScalar(value)
+		if err != nil {
+			return nil, fmt.Errorf("toml front matter line %d: %w", n+2, err)
+		}
+		fm[strings.Trim(strings.TrimSpace(key), `"`)] = v
+	}
+
+	return fm, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Posts
+
+func fmString(v interface{}) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case bool, float64:
+		return fmt.Sprint(s), nil
+	}
+	return "", fmt.Errorf("expected text, got %v", v)
+}
+
+func fmStrings(v interface{}) ([]string, error) {
+	switch list := v.(type) {
+	case string:
+		if list == "" {
+			return []string{}, nil
+		}
+		return []string{list}, nil
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			s, err := fmString(item)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("expected a list, got %v", v)
+}
+
+func fmInt(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case float64:
+		return int(n), nil
+	case string:
+		return strconv.Atoi(n)
+	}
+	return 0, fmt.Errorf("expected a number, got %v", v)
+}
+
+var frontMatterDates = []string{longformPubStr, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// Set the post fields the front matter has, leaving the rest alone
+func (bp *BlogPost) applyFrontMatter(fm map[string]interface{}) error {
+	keys := make([]string, 0, len(fm))
+	for k := range fm {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := fm[k]
+		var err error
+
+		switch strings.ToLower(k) {
+		case "title":
+			bp.Title, err = fmString(v)
+		case "key", "slug":
+			bp.Key, err = fmString(v)
+		case "date", "pubdate":
+			var s string
+			if s, err = fmString(v); err == nil {
+				err = fmt.Errorf("unable to read date %q", s)
+				for _, layout := range frontMatterDates {
+					if d, perr := time.Parse(layout, s); perr == nil {
+						bp.SetNewPubDate(d)
+						err = nil
+						break
+					}
+				}
+			}
+		case "categories", "category":
+			var cats []string
+			cats, err = fmStrings(v)
+			bp.RawCategory = []BlogCat{}
+			for _, c := range cats {
+				bp.RawCategory = append(bp.RawCategory, BlogCat(c))
+			}
+		case "smallimage", "smlimage":
+			bp.SmallImage, err = fmString(v)
+		case "bannerimage", "banner":
+			bp.BannerImage, err = fmString(v)
+		case "description", "desc":
+			bp.ShortDesc, err = fmString(v)
+		case "class", "classname":
+			bp.Class, err = fmString(v)
+		case "series":
+			bp.Series, err = fmString(v)
+		case "seriespart", "part":
+			bp.SeriesPart, err = fmInt(v)
+		case "aliases", "alias":
+			bp.Aliases, err = fmStrings(v)
+		case "draft":
+			d, ok := v.(bool)
+			if !ok {
+				err = fmt.Errorf("expected true or false, got %v", v)
+			}
+			bp.Draft = d
+		default:
+			log.Println("Unknown front matter", k, "in", bp.SourceFile)
+		}
+
+		if err != nil {
+			return fmt.Errorf("front matter %s: %w", k, err)
+		}
+	}
+
+	return nil
+}
+
+// Every body file under blogdata/post that starts with front matter.
+// A post already in blogData.js takes the front matter over its own details,
+// anything else becomes a new post. Files that can't be read go to fail
+func (bl *BlogList) mergeFrontMatter(fail func(path string, err error)) {
+	root := siteConfig.SrcPath("blogdata", "post")
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if !os.IsNotExist(err) {
+				fail(path, err)
+			}
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if info.IsDir() || (ext != ".md" && ext != ".html") {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fail(path, err)
+			return nil
+		}
+
+		fm, _, err := splitFrontMatter(data)
+		if err == nil && fm != nil {
+			err = bl.addFrontMatter(path, fm)
+		}
+		if err != nil {
+			fail(path, err)
+		}
+		return nil
+	})
+}
+
+func (bl *BlogList) addFrontMatter(path string, fm map[string]interface{}) error {
+	key := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	for _, k := range []string{"key", "slug"} {
+		if s, ok := fm[k].(string); ok && s != "" {
+			key = s
+		}
+	}
+
+	bp := bl.Get(key)
+	if bp == nil {
+		bp = &BlogPost{Key: key, RawCategory: []BlogCat{}, FrontMatter: true}
+	} else if bp.SourceFile != "" {
+		return fmt.Errorf("post %s is also in %s", key, bp.SourceFile)
+	}
+
+	bp.SourceFile = path
+	if err := bp.applyFrontMatter(fm); err != nil {
+		return err
+	}
+	if bp.Pubdate == "" {
+		return errors.New("front matter has no date")
+	}
+
+	if bp.FrontMatter && bl.Get(key) == nil {
+		*bl = append(*bl, bp)
+	}
+	return nil
+}
+
+// Front matter fields for a post, in the order they are written
+func (bp *BlogPost) frontMatterFields() [][2]interface{} {
+	fields := [][2]interface{}{
+		{"title", bp.Title},
+		{"date", bp.Pubdate},
+	}
+
+	cats := make([]string, 0, len(bp.RawCategory))
+	for _, c := range bp.RawCategory {
+		cats = append(cats, string(c))
+	}
+	fields = append(fields, [2]interface{}{"categories", cats})
+
+	for _, f := range [][2]string{
+		{"smallImage", bp.SmallImage},
+		{"bannerImage", bp.BannerImage},
+		{"description", bp.ShortDesc},
+		{"class", bp.Class},
+	} {
+		if f[1] != "" {
+			fields = append(fields, [2]interface{}{f[0], f[1]})
+		}
+	}
+
+	if bp.Series != "" {
+		fields = append(fields, [2]interface{}{"series", bp.Series})
+	}
+	if bp.SeriesPart != 0 {
+		fields = append(fields, [2]interface{}{"seriesPart", bp.SeriesPart})
+	}
+	if len(bp.Aliases) > 0 {
+		fields = append(fields, [2]interface{}{"aliases", bp.Aliases})
+	}
+	if bp.Draft {
+		fields = append(fields, [2]interface{}{"draft", true})
+	}
+	return fields
+}
+
+func quoteList(list []string) string {
+	quoted := make([]string, len(list))
+	for i, s := range list {
+		quoted[i] = strconv.Quote(s)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func (bp *BlogPost) formatFrontMatter(format string) ([]byte, error) {
+	var b bytes.Buffer
+
+	switch format {
+	case "yaml", "toml":
+		fence, sep := "---", ": "
+		if format == "toml" {
+			fence, sep = "+++", " = "
+		}
+
+		b.WriteString(fence + "\n")
+		for _, f := range bp.frontMatterFields() {
+			b.WriteString(f[0].(string) + sep)
+			switch v := f[1].(type) {
+			case string:
+				b.WriteString(strconv.Quote(v))
+			case []string:
+				b.WriteString(quoteList(v))
+			default:
+				fmt.Fprint(&b, v)
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString(fence + "\n")
+
+	case "json":
+		fm := make(map[string]interface{})
+		for _, f := range bp.frontMatterFields() {
+			fm[f[0].(string)] = f[1]
+		}
+		j, err := json.MarshalIndent(fm, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		b.Write(j)
+		b.WriteString("\n")
+
+	default:
+		return nil, fmt.Errorf("unknown front matter format %q, use one of %s", format, strings.Join(frontMatterFormats, ", "))
+	}
+
+	return b.Bytes(), nil
+}
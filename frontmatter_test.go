@@ -0,0 +1,186 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		fm   map[string]interface{}
+		body string
+	}{
+		{
+			name: "none",
+			in:   "<p>Hello</p>\n",
+			body: "<p>Hello</p>\n",
+		},
+		{
+			name: "yaml",
+			in: "---\n" +
+				"# a comment\n" +
+				"title: \"Colons: in quotes\" # and a trailing comment\n" +
+				"class: it's\n" +
+				"series: 'Jo''s # series'\n" +
+				"draft: true\n" +
+				"categories: [gamedev, \"a, b\", 'c']\n" +
+				"aliases:\n" +
+				"  - /old/\n" +
+				"  - \"/older/\"\n" +
+				"description:\n" +
+				"---\n" +
+				"Body\n",
+			fm: map[string]interface{}{
+				"title":       "Colons: in quotes",
+				"class":       "it's",
+				"series":      "Jo's # series",
+				"draft":       true,
+				"categories":  []interface{}{"gamedev", "a, b", "c"},
+				"aliases":     []interface{}{"/old/", "/older/"},
+				"description": "",
+			},
+			body: "Body\n",
+		},
+		{
+			name: "yaml crlf",
+			in:   "---\r\ntitle: Hi\r\n---\r\nBody\r\n",
+			fm:   map[string]interface{}{"title": "Hi"},
+			body: "Body\r\n",
+		},
+		{
+			name: "toml",
+			in: "+++\n" +
+				"# a comment\n" +
+				"title = \"a = b # not a comment\"\n" +
+				"\"class\" = 'quoted key'\n" +
+				"categories = [\"gamedev\", \"rpg\"]\n" +
+				"draft = false\n" +
+				"+++\n" +
+				"Body\n",
+			fm: map[string]interface{}{
+				"title":      "a = b # not a comment",
+				"class":      "quoted key",
+				"categories": []interface{}{"gamedev", "rpg"},
+				"draft":      false,
+			},
+			body: "Body\n",
+		},
+		{
+			name: "json",
+			in:   "{\n  \"title\": \"Hi: there\",\n  \"seriesPart\": 2,\n  \"categories\": [\"a\"]\n}\nBody\n",
+			fm: map[string]interface{}{
+				"title":      "Hi: there",
+				"seriesPart": float64(2),
+				"categories": []interface{}{"a"},
+			},
+			body: "Body\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, body, err := splitFrontMatter([]byte(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(fm, tt.fm) {
+				t.Errorf("front matter = %#v, want %#v", fm, tt.fm)
+			}
+			if string(body) != tt.body {
+				t.Errorf("body = %q, want %q", body, tt.body)
+			}
+		})
+	}
+}
+
+func TestSplitFrontMatterErrors(t *testing.T) {
+	for name, in := range map[string]string{
+		"unclosed yaml":   "---\ntitle: Hi\n",
+		"list no key":     "---\n- item\n---\n",
+		"not key value":   "---\njust words\n---\n",
+		"unclosed list":   "---\ncategories: [a, b\n---\n",
+		"toml table":      "+++\n[params]\n+++\n",
+		"toml no equals":  "+++\ntitle\n+++\n",
+		"bad json":        "{\"title\": }\n",
+		"unclosed quotes": "---\ntitle: \"Hi\n---\n",
+	} {
+		if _, _, err := splitFrontMatter([]byte(in)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestApplyFrontMatterEmptyValues(t *testing.T) {
+	fm, _, err := splitFrontMatter([]byte("---\ntitle:\ndescription:\ncategories:\ndate: 2020-04-01\n---\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bp := &BlogPost{Title: "Old"}
+	if err := bp.applyFrontMatter(fm); err != nil {
+		t.Fatal(err)
+	}
+	if bp.Title != "" || bp.ShortDesc != "" || len(bp.RawCategory) != 0 {
+		t.Errorf("got title %q, description %q, categories %v", bp.Title, bp.ShortDesc, bp.RawCategory)
+	}
+}
+
+func TestFrontMatterRoundTrip(t *testing.T) {
+	want := &BlogPost{
+		Title:       `Quotes "and": colons # hash`,
+		RawCategory: []BlogCat{"gamedev", "a, b"},
+		SmallImage:  "/images/small.png",
+		BannerImage: "/images/banner.png",
+		ShortDesc:   "It's a 'post'",
+		Class:       "wide",
+		Series:      "Engines",
+		SeriesPart:  3,
+		Aliases:     []string{"/old/", "/older/"},
+		Draft:       true,
+	}
+	date, err := time.Parse(longformPubStr, "Wed, 01 Apr 2020 10:30:00 +0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want.SetNewPubDate(date)
+
+	for _, format := range frontMatterFormats {
+		t.Run(format, func(t *testing.T) {
+			head, err := want.formatFrontMatter(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := frontMatterFormat(head); got != format {
+				t.Errorf("frontMatterFormat = %s", got)
+			}
+
+			fm, body, err := splitFrontMatter(append(head, "Body\n"...))
+			if err != nil {
+				t.Fatalf("%v\n%s", err, head)
+			}
+			if string(body) != "Body\n" {
+				t.Errorf("body = %q", body)
+			}
+
+			got := &BlogPost{}
+			if err := got.applyFrontMatter(fm); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.frontMatterFields(), want.frontMatterFields()) {
+				t.Errorf("got %v\nwant %v\n%s", got.frontMatterFields(), want.frontMatterFields(), head)
+			}
+			if !got.Date.Equal(want.Date) {
+				t.Errorf("date = %v, want %v", got.Date, want.Date)
+			}
+		})
+	}
+}
+
+func TestFormatFrontMatterUnknown(t *testing.T) {
+	if _, err := new(BlogPost).formatFrontMatter("xml"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// How dates are shown when a page doesn't ask for something else, e.g. "2 January 2006"
+const dateStrLayout = "2 January 2006"
+
+// Functions available to every template
+var templateFuncs = template.FuncMap{
+	"site": func() *SiteConfig { return siteConfig },
+
+	"now":        time.Now,
+	"dateFormat": dateFormat,
+
+	"absURL": func(path string) string { return siteConfig.AbsURL(path) },
+	"relURL": relURL,
+
+	"markdownify":   func(s string) template.HTML { return MarkdownToHTML([]byte(s)) },
+	"plainify":      plainify,
+	"truncateWords": truncateWords,
+	"slugify":       slugify,
+
+	"imageSize": imageSize,
+
+	"where":       where,
+	"sortBy":      sortBy,
+	"first":       first,
+	"groupByYear": groupByYear,
+
+	"jsonify": jsonify,
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Dates
+
+// Time from a time.Time or one of the date strings used in the data files
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		return *t, nil
+	case string:
+		for _, layout := range []string{longformPubStr, time.RFC3339, "2006-01-02"} {
+			if d, err := time.Parse(layout, t); err == nil {
+				return d, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("unable to read date %q", t)
+	}
+	return time.Time{}, fmt.Errorf("unable to read date from %T", v)
+}
+
+// dateFormat "Jan 2006" .Date - Go layout, or "rss" / "iso" / "default"
+func dateFormat(layout string, v interface{}) (string, error) {
+	t, err := toTime(v)
+	if err != nil {
+		return "", err
+	}
+
+	switch layout {
+	case "rss":
+		layout = longformPubStr
+	case "iso":
+		layout = time.RFC3339
+	case "default", "":
+		layout = dateStrLayout
+	}
+	return t.Format(layout), nil
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// URLs
+
+// relURL - site relative path, keeping any path in the base URL
+func relURL(path string) string {
+	base := "/"
+	if u, err := url.Parse(siteConfig.BaseURL); err == nil && u.Path != "" {
+		base = strings.TrimRight(u.Path, "/") + "/"
+	}
+	return base + strings.TrimLeft(path, "/")
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Text
+
+var stripTags = bluemonday.StripTagsPolicy()
+
+// plainify .Body - the text of some html with entities decoded and whitespace collapsed.
+// Tags become spaces so words either side of one stay apart
+func plainify(v interface{}) string {
+	plain := stripTags.Sanitize(strings.ReplaceAll(fmt.Sprint(v), "<", " <"))
+	return strings.Join(strings.Fields(html.UnescapeString(plain)), " ")
+}
+
+// truncateWords 30 .Text - cut to whole words, marked with an ellipsis
+func truncateWords(n int, v interface{}) string {
+	words := strings.Fields(fmt.Sprint(v))
+	if len(words) <= n {
+		return strings.Join(words, " ")
+	}
+	return strings.Join(words[:n], " ") + "…"
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Images
+
+type imageDims struct {
+	Width  int
+	Height int
+}
+
+// imageSize "/images/x.png" - dimensions of an image in the source folder
+func imageSize(path string) (imageDims, error) {
+	w, h, err := getImageDimension(siteConfig.SrcPath(path))
+	if err != nil {
+		return imageDims{}, err
+	}
+	return imageDims{w, h}, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Collections
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Field, map key or getter method, dotted paths walk down
+func fieldValue(item reflect.Value, path string) reflect.Value {
+	v := item
+	for _, name := range strings.Split(path, ".") {
+		if !v.IsValid() {
+			return v
+		}
+
+		m := v.MethodByName(name)
+		if m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 && m.Type().Out(0) != errorType {
+			v = m.Call(nil)[0]
+			continue
+		}
+
+		v = indirect(v)
+		switch v.Kind() {
+		case reflect.Struct:
+			v = v.FieldByName(name)
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(name))
+		default:
+			return reflect.Value{}
+		}
+	}
+	return indirect(v)
+}
+
+func sliceValue(list interface{}) (reflect.Value, error) {
+	v := indirect(reflect.ValueOf(list))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return reflect.Value{}, fmt.Errorf("can't iterate over %T", list)
+	}
+	return v, nil
+}
+
+func matches(field reflect.Value, want interface{}) bool {
+	if !field.IsValid() {
+		return false
+	}
+
+	// A list field matches if any of it does, e.g. where .Feed "Category" "rpg"
+	if field.Kind() == reflect.Slice && field.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < field.Len(); i++ {
+			if matches(indirect(field.Index(i)), want) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return fmt.Sprint(field.Interface()) == fmt.Sprint(want)
+}
+
+// where .Feed "Class" "review" - items whose field equals the value
+func where(list interface{}, path string, want interface{}) (interface{}, error) {
+	v, err := sliceValue(list)
+	if err != nil {
+		return nil, err
+	}
+
+	out := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if matches(fieldValue(v.Index(i), path), want) {
+			out = reflect.Append(out, v.Index(i))
+		}
+	}
+	return out.Interface(), nil
+}
+
+func lessValue(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return !a.IsValid() && b.IsValid()
+	}
+
+	if ta, ok := a.Interface().(time.Time); ok {
+		if tb, ok := b.Interface().(time.Time); ok {
+			return ta.Before(tb)
+		}
+	}
+
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// sortBy .Feed "Title" ["desc"] - a sorted copy
+func sortBy(list interface{}, path string, order ...string) (interface{}, error) {
+	v, err := sliceValue(list)
+	if err != nil {
+		return nil, err
+	}
+
+	out := reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), v.Len(), v.Len())
+	reflect.Copy(out, v)
+
+	desc := len(order) > 0 && order[0] == "desc"
+	sort.SliceStable(out.Interface(), func(i, j int) bool {
+		a, b := fieldValue(out.Index(i), path), fieldValue(out.Index(j), path)
+		if desc {
+			return lessValue(b, a)
+		}
+		return lessValue(a, b)
+	})
+	return out.Interface(), nil
+}
+
+// first 5 .Feed
+func first(n int, list interface{}) (interface{}, error) {
+	v, err := sliceValue(list)
+	if err != nil {
+		return nil, err
+	}
+	return v.Slice(0, max(0, min(n, v.Len()))).Interface(), nil
+}
+
+type YearGroup struct {
+	Year  int
+	Items interface{}
+}
+
+// groupByYear .Feed - newest year first, items keep their order, grouped on Date
+func groupByYear(list interface{}) ([]YearGroup, error) {
+	v, err := sliceValue(list)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []YearGroup
+	lists := make(map[int]reflect.Value)
+	for i := 0; i < v.Len(); i++ {
+		date := fieldValue(v.Index(i), "Date")
+		if !date.IsValid() {
+			return nil, fmt.Errorf("no Date to group %s by", v.Index(i).Type())
+		}
+		t, err := toTime(date.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		year := t.Year()
+		if _, ok := lists[year]; !ok {
+			lists[year] = reflect.MakeSlice(reflect.SliceOf(v.Type().Elem()), 0, 0)
+			groups = append(groups, YearGroup{Year: year})
+		}
+		lists[year] = reflect.Append(lists[year], v.Index(i))
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Year > groups[j].Year })
+	for i := range groups {
+		groups[i].Items = lists[groups[i].Year].Interface()
+	}
+	return groups, nil
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// JSON
+
+func jsonify(v interface{}) (template.JS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(b), nil
+}
@@ -5,10 +5,10 @@ go 1.21
 require (
 	github.com/microcosm-cc/bluemonday v1.0.14
 	github.com/russross/blackfriday v1.6.0
+	golang.org/x/net v0.0.0-20210610132358-84b48f89b13b
 )
 
 require (
 	github.com/aymerick/douceur v0.2.0 // indirect
 	github.com/gorilla/css v1.0.0 // indirect
-	golang.org/x/net v0.0.0-20210610132358-84b48f89b13b // indirect
 )
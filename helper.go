@@ -6,7 +6,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
 	"runtime/debug"
+	"sort"
+	"sync"
 )
 
 // Copy a directory tree from `src` to `dest`
@@ -25,6 +28,9 @@ func CopyTree(src string, dest string, verbose bool) error {
 		}
 
 		myDest = filepath.Join(dest, myDest)
+		if verbose {
+			log.Println("Copy", path, ">", myDest)
+		}
 
 		if info.IsDir() {
 			err = os.Mkdir(myDest, info.Mode())
@@ -44,6 +50,34 @@ func CopyTree(src string, dest string, verbose bool) error {
 	return finalErr
 }
 
+// Mirror `src` into `dest` using hard links, falling back to copies
+func LinkTree(src string, dest string) error {
+	src = filepath.Clean(src)
+	dest = filepath.Clean(dest)
+
+	return filepath.Walk(src, func(path string, info os.FileInfo, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0777)
+		}
+
+		if os.Link(path, target) == nil {
+			return nil
+		}
+		_, err = CopyFileLazy(path, target)
+		return err
+	})
+}
+
 func CopyFileLazy(src string, dest string) (int64, error) {
 
 	srcInfo, err := os.Stat(src)
@@ -53,8 +87,8 @@ func CopyFileLazy(src string, dest string) (int64, error) {
 	}
 
 	destInfo, err := os.Stat(dest)
-	if err != nil && destInfo != nil {
-		if (destInfo.ModTime() == srcInfo.ModTime()) && (destInfo.Size() == srcInfo.Size()) {
+	if err == nil {
+		if destInfo.ModTime().Equal(srcInfo.ModTime()) && (destInfo.Size() == srcInfo.Size()) {
 			return destInfo.Size(), nil
 		}
 	}
@@ -66,6 +100,8 @@ func CopyFileLazy(src string, dest string) (int64, error) {
 	}
 	defer source.Close()
 
+	// Remove first as the file may be hard linked to the previous build
+	os.Remove(dest)
 	destination, err := os.Create(dest)
 	if err != nil {
 		fmt.Println("Error Writing:" + src)
@@ -81,13 +117,150 @@ func CopyFileLazy(src string, dest string) (int64, error) {
 	if nBytes != srcInfo.Size() {
 		return 0, fmt.Errorf("failed to copy %d != %d", nBytes, srcInfo.Size())
 	}
+
+	// Match the source time so the next copy can be skipped
+	destination.Close()
+	err = os.Chtimes(dest, srcInfo.ModTime(), srcInfo.ModTime())
 	return nBytes, err
 
 }
 
+// Run fn for every index in 0..n-1 on the worker pool and wait for them all
+func runParallel(n int, fn func(i int)) {
+	workers := siteConfig.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	if workers > n {
+		workers = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Build Errors - collected so one bad post or file doesn't stop the build
+
+type BuildError struct {
+	Item    string
+	File    string
+	Err     error
+	Warning bool
+}
+
+type BuildErrors struct {
+	list []BuildError
+	lock sync.Mutex
+}
+
+var buildErrors = &BuildErrors{}
+
+// Errors still go to stderr when -q discards the rest of the log
+var errorLog = log.New(os.Stderr, "", log.LstdFlags)
+
+// Record a failure and carry on, the build won't be swapped in
+func reportError(item string, file string, err error) {
+	buildErrors.add(BuildError{item, file, err, false})
+}
+
+// Record something wrong the page was still built around, it doesn't fail the build
+func reportWarning(item string, file string, err error) {
+	buildErrors.add(BuildError{item, file, err, true})
+}
+
+func (be *BuildErrors) add(e BuildError) {
+	if e.Warning {
+		errorLog.Println("WARNING", e.Item, e.File, e.Err)
+	} else {
+		errorLog.Println("ERROR", e.Item, e.File, e.Err)
+	}
+
+	be.lock.Lock()
+	defer be.lock.Unlock()
+	be.list = append(be.list, e)
+}
+
+func (be *BuildErrors) Reset() {
+	be.lock.Lock()
+	defer be.lock.Unlock()
+	be.list = nil
+}
+
+// Count - failures, leaving out warnings
+func (be *BuildErrors) Count() int {
+	return be.count(false)
+}
+
+func (be *BuildErrors) Warnings() int {
+	return be.count(true)
+}
+
+func (be *BuildErrors) count(warning bool) int {
+	be.lock.Lock()
+	defer be.lock.Unlock()
+
+	n := 0
+	for _, e := range be.list {
+		if e.Warning == warning {
+			n++
+		}
+	}
+	return n
+}
+
+// Print every failure then every warning, sorted so parallel builds report the same way
+func (be *BuildErrors) Print(w io.Writer) {
+	be.lock.Lock()
+	defer be.lock.Unlock()
+
+	sort.SliceStable(be.list, func(i, j int) bool {
+		if be.list[i].Item != be.list[j].Item {
+			return be.list[i].Item < be.list[j].Item
+		}
+		return be.list[i].File < be.list[j].File
+	})
+
+	for _, warning := range []bool{false, true} {
+		var list []BuildError
+		for _, e := range be.list {
+			if e.Warning == warning {
+				list = append(list, e)
+			}
+		}
+		if len(list) == 0 {
+			continue
+		}
+
+		if warning {
+			fmt.Fprintf(w, "---- %d WARNING(S) ----\n", len(list))
+		} else {
+			fmt.Fprintf(w, "---- %d BUILD FAILURE(S) ----\n", len(list))
+		}
+		for _, e := range list {
+			fmt.Fprintf(w, "%s\n  file:   %s\n  reason: %v\n", e.Item, e.File, e.Err)
+		}
+	}
+}
+
 func CheckErr(err error) {
 	if err != nil {
-		log.Fatalf(`
+		errorLog.Fatalf(`
 ---- STACK -------------
 %s
 ----  END  -------------
@@ -98,7 +271,7 @@ func CheckErr(err error) {
 
 func CheckErrContext(err error, context ...string) {
 	if err != nil {
-		log.Fatalf(`
+		errorLog.Fatalf(`
 ---- STACK -------------
 %s
 ----  END  -------------
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ManifestEntry - what an output file was last built from
+type ManifestEntry struct {
+	Inputs  string   `json:"inputs,omitempty"`
+	Output  string   `json:"output,omitempty"`
+	Sources []string `json:"sources,omitempty"`
+}
+
+// BuildManifest - hashes of every output from the last build so unchanged
+// pages can be skipped and outputs that were not produced again removed.
+// A nil manifest treats everything as changed and records nothing.
+type BuildManifest struct {
+	OutputDir string                    `json:"outputDir"`
+	Global    string                    `json:"global"`
+	Outputs   map[string]*ManifestEntry `json:"outputs"`
+
+	previous map[string]*ManifestEntry
+	lock     sync.Mutex
+}
+
+const manifestFile = ".buildmanifest.json"
+
+var (
+	buildManifest *BuildManifest
+	fullBuild     bool
+)
+
+func manifestPath() string {
+	return siteConfig.SrcPath(manifestFile)
+}
+
+func newBuildManifest() *BuildManifest {
+	return &BuildManifest{
+		OutputDir: filepath.ToSlash(filepath.Clean(siteConfig.OutputDir)),
+		Global:    globalInputHash(),
+		Outputs:   make(map[string]*ManifestEntry),
+		previous:  make(map[string]*ManifestEntry),
+	}
+}
+
+// Load the manifest for the current output folder, a fresh one if there isn't one
+func loadBuildManifest() *BuildManifest {
+	m := newBuildManifest()
+
+	var old BuildManifest
+	jsonBlob, err := os.ReadFile(manifestPath())
+	if err != nil {
+		log.Println("No build manifest, building everything")
+		return m
+	}
+
+	err = json.Unmarshal(jsonBlob, &old)
+	if err != nil || old.OutputDir != m.OutputDir || old.Outputs == nil {
+		log.Println("Build manifest is unusable, building everything")
+		return m
+	}
+
+	m.previous = old.Outputs
+	return m
+}
+
+func (m *BuildManifest) Save() error {
+	if m == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return saveJSONBlob(manifestPath(), m)
+}
+
+func outputKey(rel string) string {
+	return strings.TrimPrefix(filepath.ToSlash(filepath.Clean(rel)), "/")
+}
+
+func (m *BuildManifest) entry(key string) *ManifestEntry {
+	e, ok := m.Outputs[key]
+	if !ok {
+		e = &ManifestEntry{}
+		m.Outputs[key] = e
+	}
+	return e
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Hashing
+
+func hashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// Templates and config feed into every page
+func globalInputHash() string {
+	h := sha256.New()
+
+	cfg, _ := json.Marshal(siteConfig)
+	h.Write(cfg)
+
+	hashTheme(h)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// InputHash - hash of the data a page is rendered from
+func (m *BuildManifest) InputHash(data ...interface{}) string {
+	if m == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	io.WriteString(h, m.Global)
+	for _, d := range data {
+		b, err := json.Marshal(d)
+		if err != nil {
+			// Can't hash it so never consider it fresh
+			return ""
+		}
+		h.Write(b)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Tracking
+
+// Fresh - true if the output was built from the same inputs last time and is still on disk.
+// A fresh output is carried over into this build's manifest.
+func (m *BuildManifest) Fresh(rel string, inputs string) bool {
+	if m == nil || inputs == "" || fullBuild {
+		return false
+	}
+
+	key := outputKey(rel)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	old, ok := m.previous[key]
+	if !ok || old.Inputs != inputs {
+		return false
+	}
+
+	if _, err := os.Stat(filepath.Join(publicHtmlRoot, key)); err != nil {
+		return false
+	}
+
+	m.Outputs[key] = old
+	return true
+}
+
+// Unchanged - true if the output already on disk has this content
+func (m *BuildManifest) Unchanged(rel string, output string) bool {
+	if m == nil || fullBuild {
+		return false
+	}
+
+	key := outputKey(rel)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	old, ok := m.previous[key]
+	if !ok || old.Output != output {
+		return false
+	}
+
+	if _, err := os.Stat(filepath.Join(publicHtmlRoot, key)); err != nil {
+		return false
+	}
+
+	m.entry(key).Output = output
+	return true
+}
+
+func (m *BuildManifest) RecordOutput(rel string, output string) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.entry(outputKey(rel)).Output = output
+}
+
+func (m *BuildManifest) RecordInputs(rel string, inputs string, sources ...string) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	e := m.entry(outputKey(rel))
+	e.Inputs = inputs
+	e.Sources = sources
+}
+
+// Keep - an output managed elsewhere (copied files) that belongs to this build
+func (m *BuildManifest) Keep(rel string, sources ...string) {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.entry(outputKey(rel)).Sources = sources
+}
+
+// Prune - remove outputs of the last build that weren't produced this time
+func (m *BuildManifest) Prune() {
+	if m == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for key := range m.previous {
+		if _, ok := m.Outputs[key]; ok {
+			continue
+		}
+
+		path := filepath.Join(publicHtmlRoot, key)
+		log.Println("Removing stale", key)
+		err := os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			errorLog.Println("Unable to remove", path, err)
+			continue
+		}
+
+		// Tidy up folders left empty
+		root := filepath.Clean(publicHtmlRoot)
+		for dir := filepath.Dir(path); dir != root && dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// A source and output folder under a temp dir, put back how they were when the test ends
+func useTestSite(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	oldConfig, oldRoot, oldFull := siteConfig, publicHtmlRoot, fullBuild
+	t.Cleanup(func() { siteConfig, publicHtmlRoot, fullBuild = oldConfig, oldRoot, oldFull })
+
+	cfg := defaultSiteConfig()
+	cfg.SourceDir = filepath.Join(dir, "src")
+	cfg.OutputDir = filepath.Join(dir, "public_html")
+	applySiteConfig(cfg)
+	fullBuild = false
+
+	for _, d := range []string{cfg.SourceDir, cfg.OutputDir} {
+		if err := os.MkdirAll(d, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// Files with the given content, paths relative to root
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for rel, data := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// Every file under root, relative and sorted
+func listTestFiles(t *testing.T, root string) []string {
+	t.Helper()
+	var files []string
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			rel, _ := filepath.Rel(root, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files
+}
+
+func TestManifestFresh(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous string // inputs recorded last build, "" for none
+		onDisk   bool
+		inputs   string
+		full     bool
+		want     bool
+	}{
+		{"same inputs", "abc", true, "abc", false, true},
+		{"changed inputs", "abc", true, "xyz", false, false},
+		{"not built before", "", true, "abc", false, false},
+		{"output deleted", "abc", false, "abc", false, false},
+		{"no inputs", "", true, "", false, false},
+		{"full build", "abc", true, "abc", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSite(t)
+			fullBuild = tt.full
+			if tt.onDisk {
+				writeTestFiles(t, publicHtmlRoot, map[string]string{"blog/index.html": "old"})
+			}
+
+			m := newBuildManifest()
+			if tt.previous != "" {
+				m.previous["blog/index.html"] = &ManifestEntry{Inputs: tt.previous, Output: "out"}
+			}
+
+			if got := m.Fresh("/blog/index.html", tt.inputs); got != tt.want {
+				t.Errorf("Fresh = %v, want %v", got, tt.want)
+			}
+			// A fresh output stays in the manifest, anything else waits to be recorded
+			if _, kept := m.Outputs["blog/index.html"]; kept != tt.want {
+				t.Errorf("carried over = %v, want %v", kept, tt.want)
+			}
+		})
+	}
+
+	var m *BuildManifest
+	if m.Fresh("a.html", "abc") {
+		t.Error("nil manifest is never fresh")
+	}
+}
+
+func TestManifestUnchanged(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		onDisk bool
+		full   bool
+		want   bool
+	}{
+		{"same content", "hash", true, false, true},
+		{"new content", "other", true, false, false},
+		{"output deleted", "hash", false, false, false},
+		{"full build", "hash", true, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSite(t)
+			fullBuild = tt.full
+			if tt.onDisk {
+				writeTestFiles(t, publicHtmlRoot, map[string]string{"rss.xml": "old"})
+			}
+
+			m := newBuildManifest()
+			m.previous["rss.xml"] = &ManifestEntry{Output: "hash"}
+			if got := m.Unchanged("rss.xml", tt.output); got != tt.want {
+				t.Errorf("Unchanged = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManifestInputHash(t *testing.T) {
+	m := &BuildManifest{Global: "g"}
+	a := m.InputHash("title", []int{1, 2})
+	if a == "" || a != m.InputHash("title", []int{1, 2}) {
+		t.Fatal("same data should give the same hash")
+	}
+	if a == m.InputHash("title", []int{2, 1}) {
+		t.Error("different data gave the same hash")
+	}
+	if a == (&BuildManifest{Global: "other"}).InputHash("title", []int{1, 2}) {
+		t.Error("templates and config changing should change every hash")
+	}
+	if m.InputHash(func() {}) != "" {
+		t.Error("data that can't be hashed should never be fresh")
+	}
+}
+
+func TestManifestPrune(t *testing.T) {
+	tests := []struct {
+		name     string
+		previous []string
+		built    []string
+		want     []string
+		dirGone  bool
+	}{
+		{
+			name:     "stale outputs removed",
+			previous: []string{"a.html", "blog/old/index.html", "keep.html"},
+			built:    []string{"keep.html"},
+			want:     []string{"hand.txt", "keep.html"},
+			dirGone:  true,
+		},
+		{
+			name:     "nothing stale",
+			previous: []string{"a.html", "keep.html"},
+			built:    []string{"a.html", "keep.html"},
+			want:     []string{"a.html", "blog/old/index.html", "hand.txt", "keep.html"},
+		},
+		{
+			name:  "first build leaves everything",
+			built: []string{"keep.html"},
+			want:  []string{"a.html", "blog/old/index.html", "hand.txt", "keep.html"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSite(t)
+			writeTestFiles(t, publicHtmlRoot, map[string]string{
+				"a.html":              "a",
+				"blog/old/index.html": "old",
+				"keep.html":           "keep",
+				"hand.txt":            "put here by hand",
+			})
+
+			m := newBuildManifest()
+			for _, key := range tt.previous {
+				m.previous[key] = &ManifestEntry{}
+			}
+			for _, key := range tt.built {
+				m.RecordOutput(key, "hash")
+			}
+			m.Prune()
+
+			got := listTestFiles(t, publicHtmlRoot)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("left %v, want %v", got, tt.want)
+			}
+			if _, err := os.Stat(filepath.Join(publicHtmlRoot, "blog")); os.IsNotExist(err) != tt.dirGone {
+				t.Errorf("blog folder removed = %v, want %v", os.IsNotExist(err), tt.dirGone)
+			}
+		})
+	}
+}
+
+func TestLoadBuildManifest(t *testing.T) {
+	useTestSite(t)
+
+	m := newBuildManifest()
+	m.RecordInputs("blog/index.html", "inputs", "blogdata/blogData.js")
+	if err := m.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := loadBuildManifest()
+	e, ok := loaded.previous["blog/index.html"]
+	if !ok || e.Inputs != "inputs" || len(loaded.Outputs) != 0 {
+		t.Errorf("loaded previous %v, outputs %v", loaded.previous, loaded.Outputs)
+	}
+
+	// A manifest for another output folder is no use
+	siteConfig.OutputDir = filepath.Join(filepath.Dir(siteConfig.OutputDir), "elsewhere")
+	if other := loadBuildManifest(); len(other.previous) != 0 {
+		t.Errorf("manifest for another folder was used: %v", other.previous)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// //////////////////////////////////////////////////////////////////////////////
+// Output - every generated file is written through here
+
+func outputPath(rel string) string {
+	return filepath.Join(publicHtmlRoot, rel)
+}
+
+func makeOutputDir(rel string) error {
+	if dryRunPlan != nil {
+		return nil
+	}
+	return os.MkdirAll(outputPath(rel), 0777)
+}
+
+// Write a file under the output root, skipped if the content hasn't changed
+func writeOutputFile(rel string, data []byte) error {
+	buildReport.Output(rel, currentSection, "")
+	if dryRunPlan != nil {
+		dryRunPlan.Write(rel, data)
+		return nil
+	}
+
+	hash := hashBytes(data)
+	if buildManifest.Unchanged(rel, hash) {
+		return nil
+	}
+
+	path := outputPath(rel)
+	err := os.MkdirAll(filepath.Dir(path), 0777)
+	if err != nil {
+		return err
+	}
+
+	// Remove first as the file may be hard linked to the previous build
+	os.Remove(path)
+	err = os.WriteFile(path, data, 0666)
+	if err != nil {
+		return err
+	}
+
+	buildManifest.RecordOutput(rel, hash)
+	return nil
+}
+
+// Copy a source file to the output root
+func copyOutputFile(src string, rel string) error {
+	buildReport.Output(rel, currentSection, src)
+	if dryRunPlan != nil {
+		return dryRunPlan.Copy(src, rel)
+	}
+
+	err := makeOutputDir(filepath.Dir(rel))
+	if err != nil {
+		return err
+	}
+
+	_, err = CopyFileLazy(src, outputPath(rel))
+	return err
+}
+
+// Render a page template in its layout and write it out
+func writePage(rel string, name string, data interface{}, frameData *SubPage) error {
+	var outBuffer bytes.Buffer
+	err := renderPage(&outBuffer, name, data, frameData)
+	if err != nil {
+		return err
+	}
+
+	return writeOutputFile(rel, outBuffer.Bytes())
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Dry Run - compare against the live output instead of touching the disk
+
+type DryRunPlan struct {
+	Created []string
+	Changed []string
+	Removed []string
+
+	planned map[string]bool
+	lock    sync.Mutex
+}
+
+var (
+	dryRun     bool
+	dryRunPlan *DryRunPlan
+)
+
+func newDryRunPlan() *DryRunPlan {
+	return &DryRunPlan{planned: make(map[string]bool)}
+}
+
+func (p *DryRunPlan) record(rel string, exists bool, same bool) {
+	key := outputKey(rel)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.planned[key] = true
+	if !exists {
+		p.Created = append(p.Created, key)
+	} else if !same {
+		p.Changed = append(p.Changed, key)
+	}
+}
+
+func (p *DryRunPlan) Write(rel string, data []byte) {
+	old, err := os.ReadFile(outputPath(rel))
+	p.record(rel, err == nil, err == nil && bytes.Equal(old, data))
+}
+
+func (p *DryRunPlan) Copy(src string, rel string) error {
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	// Same test as CopyFileLazy before falling back to the content
+	destInfo, err := os.Stat(outputPath(rel))
+	if err == nil && destInfo.ModTime().Equal(srcInfo.ModTime()) && destInfo.Size() == srcInfo.Size() {
+		p.record(rel, true, true)
+		return nil
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	p.Write(rel, data)
+	return nil
+}
+
+// What the real build would remove. Like BuildManifest.Prune that is whatever the last
+// build made and this one doesn't, files put there by hand stay. A full build starts
+// from an empty folder so anything it doesn't make is gone
+func (p *DryRunPlan) FindRemoved() {
+	removed := func(rel string) {
+		if !p.planned[outputKey(rel)] {
+			p.Removed = append(p.Removed, outputKey(rel))
+		}
+	}
+
+	if !fullBuild {
+		for key := range loadBuildManifest().previous {
+			if _, err := os.Stat(outputPath(key)); err == nil {
+				removed(key)
+			}
+		}
+		return
+	}
+
+	root := filepath.Clean(publicHtmlRoot)
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		removed(rel)
+		return nil
+	})
+}
+
+func (p *DryRunPlan) Print(w io.Writer) {
+	for _, list := range [][]string{p.Created, p.Changed, p.Removed} {
+		sort.Strings(list)
+	}
+
+	for _, f := range p.Created {
+		fmt.Fprintln(w, "+ "+f)
+	}
+	for _, f := range p.Changed {
+		fmt.Fprintln(w, "~ "+f)
+	}
+	for _, f := range p.Removed {
+		fmt.Fprintln(w, "- "+f)
+	}
+	fmt.Fprintf(w, "Dry run: %d created, %d changed, %d removed, %d unchanged\n",
+		len(p.Created), len(p.Changed), len(p.Removed), len(p.planned)-len(p.Created)-len(p.Changed))
+}
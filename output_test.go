@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDryRunPlanFindRemoved(t *testing.T) {
+	tests := []struct {
+		name     string
+		full     bool
+		previous []string // outputs of the last build, nil for no manifest
+		want     []string
+	}{
+		{
+			name:     "stale outputs",
+			previous: []string{"a.html", "b.html", "blog/c.html", "gone.html"},
+			want:     []string{"b.html", "blog/c.html"},
+		},
+		{
+			name: "no manifest",
+		},
+		{
+			name:     "full build",
+			full:     true,
+			previous: []string{"a.html"},
+			want:     []string{"b.html", "blog/c.html", "hand.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSite(t)
+			writeTestFiles(t, publicHtmlRoot, map[string]string{
+				"a.html":      "a",
+				"b.html":      "b",
+				"blog/c.html": "c",
+				"hand.txt":    "put here by hand",
+			})
+			if tt.previous != nil {
+				m := newBuildManifest()
+				for _, key := range tt.previous {
+					m.RecordOutput(key, "hash")
+				}
+				if err := m.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fullBuild = tt.full
+
+			p := newDryRunPlan()
+			p.Write("/a.html", []byte("a"))
+			p.FindRemoved()
+
+			sort.Strings(p.Removed)
+			if !reflect.DeepEqual(p.Removed, tt.want) {
+				t.Errorf("removed = %v, want %v", p.Removed, tt.want)
+			}
+		})
+	}
+}
+
+func TestDryRunPlanWrite(t *testing.T) {
+	useTestSite(t)
+	writeTestFiles(t, publicHtmlRoot, map[string]string{"same.html": "same", "changed.html": "old"})
+
+	p := newDryRunPlan()
+	p.Write("same.html", []byte("same"))
+	p.Write("changed.html", []byte("new"))
+	p.Write("blog/new.html", []byte("new"))
+
+	if !reflect.DeepEqual(p.Created, []string{"blog/new.html"}) || !reflect.DeepEqual(p.Changed, []string{"changed.html"}) {
+		t.Errorf("created %v, changed %v", p.Created, p.Changed)
+	}
+	if files := listTestFiles(t, publicHtmlRoot); len(files) != 2 {
+		t.Errorf("dry run wrote files: %v", files)
+	}
+}
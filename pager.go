@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Pager - where a page sits in a list split over several pages
+type Pager struct {
+	Current    int
+	Total      int
+	PageSize   int
+	TotalItems int
+
+	URL     string
+	PrevURL string // newer posts, empty on the first page
+	NextURL string // older posts, empty on the last page
+
+	Pages []PagerLink
+}
+
+// PagedList - what a paged list's template gets, old style templates have no frame to find the pager on
+type PagedList struct {
+	Posts BlogList
+	Pager *Pager
+}
+
+type PagerLink struct {
+	Number int
+	URL    string
+}
+
+// First page is the list's own URL, the rest are <url>page/N/
+func pageURL(baseURL string, n int) string {
+	if n <= 1 {
+		return baseURL
+	}
+	return fmt.Sprintf("%spage/%d/", baseURL, n)
+}
+
+// Split a list into pages of siteConfig.PageSize, always at least one page
+func paginate(bl BlogList, baseURL string) ([]BlogList, []*Pager) {
+	size := siteConfig.PageSize
+	if size <= 0 || size > len(bl) {
+		size = max(1, len(bl))
+	}
+
+	total := max(1, (len(bl)+size-1)/size)
+	links := make([]PagerLink, total)
+	for i := range links {
+		links[i] = PagerLink{i + 1, pageURL(baseURL, i+1)}
+	}
+
+	pages := make([]BlogList, total)
+	pagers := make([]*Pager, total)
+	for i := range pages {
+		pages[i] = bl[min(i*size, len(bl)):min((i+1)*size, len(bl))]
+
+		p := &Pager{
+			Current:    i + 1,
+			Total:      total,
+			PageSize:   size,
+			TotalItems: len(bl),
+			URL:        links[i].URL,
+			Pages:      links,
+		}
+		if i > 0 {
+			p.PrevURL = links[i-1].URL
+		}
+		if i+1 < total {
+			p.NextURL = links[i+1].URL
+		}
+		pagers[i] = p
+	}
+
+	return pages, pagers
+}
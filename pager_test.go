@@ -0,0 +1,85 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name  string
+		posts int
+		size  int
+		sizes []int    // posts on each page
+		urls  []string // each page's URL
+	}{
+		{"one page", 3, 0, []int{3}, []string{"/blog/"}},
+		{"empty list", 0, 2, []int{0}, []string{"/blog/"}},
+		{"exact fit", 4, 2, []int{2, 2}, []string{"/blog/", "/blog/page/2/"}},
+		{"short last page", 5, 2, []int{2, 2, 1}, []string{"/blog/", "/blog/page/2/", "/blog/page/3/"}},
+		{"size bigger than list", 2, 10, []int{2}, []string{"/blog/"}},
+		{"negative size", 3, -1, []int{3}, []string{"/blog/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useTestSite(t)
+			siteConfig.PageSize = tt.size
+
+			bl := make(BlogList, tt.posts)
+			for i := range bl {
+				bl[i] = &BlogPost{Key: string(rune('a' + i))}
+			}
+
+			pages, pagers := paginate(bl, "/blog/")
+			if len(pages) != len(pagers) {
+				t.Fatalf("%d pages but %d pagers", len(pages), len(pagers))
+			}
+
+			var sizes []int
+			var urls []string
+			var seen BlogList
+			for i, page := range pages {
+				sizes = append(sizes, len(page))
+				urls = append(urls, pagers[i].URL)
+				seen = append(seen, page...)
+			}
+			if !reflect.DeepEqual(sizes, tt.sizes) {
+				t.Errorf("page sizes = %v, want %v", sizes, tt.sizes)
+			}
+			if !reflect.DeepEqual(urls, tt.urls) {
+				t.Errorf("urls = %v, want %v", urls, tt.urls)
+			}
+			if len(seen) != len(bl) || (len(bl) > 0 && !reflect.DeepEqual(seen, bl)) {
+				t.Errorf("pages don't cover the list in order")
+			}
+		})
+	}
+}
+
+func TestPaginateLinks(t *testing.T) {
+	useTestSite(t)
+	siteConfig.PageSize = 2
+
+	bl := make(BlogList, 5)
+	_, pagers := paginate(bl, "/blog/cat/go/")
+
+	tests := []struct {
+		current int
+		prev    string
+		next    string
+	}{
+		{1, "", "/blog/cat/go/page/2/"},
+		{2, "/blog/cat/go/", "/blog/cat/go/page/3/"},
+		{3, "/blog/cat/go/page/2/", ""},
+	}
+	for i, tt := range tests {
+		p := pagers[i]
+		if p.Current != tt.current || p.PrevURL != tt.prev || p.NextURL != tt.next {
+			t.Errorf("page %d: current %d, prev %q, next %q", i+1, p.Current, p.PrevURL, p.NextURL)
+		}
+		if p.Total != 3 || p.PageSize != 2 || p.TotalItems != 5 || len(p.Pages) != 3 {
+			t.Errorf("page %d: %+v", i+1, p)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"html/template"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// PostStats - counted from a post's body when it loads
+type PostStats struct {
+	Words       int `json:"words"`
+	ReadingTime int `json:"readingMinutes"`
+	Images      int `json:"images"`
+	CodeBlocks  int `json:"codeBlocks"`
+}
+
+const wordsPerMinute = 200
+
+func isWord(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Words outside code blocks, <img>s and <pre>s, reading time rounded up
+func bodyStats(body template.HTML) PostStats {
+	var st PostStats
+	inCode, skip := 0, 0
+
+	z := html.NewTokenizer(strings.NewReader(string(body)))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		name, _ := z.TagName()
+		a := atom.Lookup(name)
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			switch a {
+			case atom.Img:
+				st.Images++
+			case atom.Pre:
+				st.CodeBlocks++
+				inCode++
+			case atom.Script, atom.Style:
+				skip++
+			}
+		case html.EndTagToken:
+			switch a {
+			case atom.Pre:
+				inCode = max(0, inCode-1)
+			case atom.Script, atom.Style:
+				skip = max(0, skip-1)
+			}
+		case html.TextToken:
+			if inCode == 0 && skip == 0 {
+				for _, w := range strings.Fields(string(z.Text())) {
+					if isWord(w) {
+						st.Words++
+					}
+				}
+			}
+		}
+	}
+
+	if st.Words > 0 {
+		st.ReadingTime = max(1, (st.Words+wordsPerMinute-1)/wordsPerMinute)
+	}
+	return st
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Summaries
+
+var regMoreMarker = regexp.MustCompile(`<!--\s*more\s*-->`)
+
+// Everything before a <!--more--> marker, which should be on a line of its own
+func splitExcerpt(body template.HTML) (template.HTML, bool) {
+	loc := regMoreMarker.FindStringIndex(string(body))
+	if loc == nil {
+		return "", false
+	}
+	return template.HTML(strings.TrimSpace(string(body[:loc[0]]))), true
+}
+
+// Plain text of the body cut to whole words within maxRunes, marked with an ellipsis
+func summarize(body template.HTML, maxRunes int) string {
+	text := plainify(body)
+
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+
+	// Back up to a space unless the cut already ends a word
+	cut := string(runes[:maxRunes])
+	if i := strings.LastIndex(cut, " "); i > 0 && runes[maxRunes] != ' ' {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,;:-") + "…"
+}
+
+// Work out what comes from the body once it has loaded
+func (bp *BlogPost) bodyLoaded() {
+	bp.Stats = bodyStats(bp.Body)
+	bp.Excerpt, bp.HasMore = splitExcerpt(bp.Body)
+}
+
+// The excerpt if there is one, or the start of the body
+func (bp *BlogPost) summary(maxRunes int) string {
+	if bp.HasMore {
+		return summarize(bp.Excerpt, maxRunes)
+	}
+	return summarize(bp.Body, maxRunes)
+}
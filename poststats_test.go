@@ -0,0 +1,90 @@
+package main
+
+import (
+	"html/template"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   template.HTML
+		max  int
+		want string
+	}{
+		{"short", "<p>Hello <b>world</b></p>", 200, "Hello world"},
+		{"blocks kept apart", "<h2>Title</h2><p>one</p><ul><li>two</li><li>three</li></ul>", 200, "Title one two three"},
+		{"entities", "<p>Fish &amp; chips &lt;3 &quot;quoted&quot; caf&eacute; &#8212; &#x263A;</p>", 200, `Fish & chips <3 "quoted" café — ☺`},
+		{"whitespace runs", "  <p>a\n\n\tb</p>   <p>   c  </p>\r\n", 200, "a b c"},
+		{"scripts and comments dropped", "<p>a</p><script>var x = 1;</script><style>p{}</style><!-- note --><p>b</p>", 200, "a b"},
+		{"cut on a word", "<p>one two three four</p>", 12, "one two…"},
+		{"cut at a word end", "<p>one two three</p>", 7, "one two…"},
+		{"cut at a space", "<p>one two three</p>", 8, "one two…"},
+		{"cut one short of a word end", "<p>one two three</p>", 6, "one…"},
+		{"trailing punctuation", "<p>one, two three</p>", 6, "one…"},
+		{"multi-byte word cut", "<p>héllo wörld ünïcode</p>", 13, "héllo wörld…"},
+		{"multi-byte no spaces", "<p>日本語のテキストです</p>", 4, "日本語の…"},
+		{"emoji", "<p>🎲🎲🎲 dice</p>", 2, "🎲🎲…"},
+		{"exactly max", "<p>héllo</p>", 5, "héllo"},
+		{"empty", "", 10, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarize(tt.in, tt.max)
+			if got != tt.want {
+				t.Errorf("summarize = %q, want %q", got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("summarize = %q is not valid UTF-8", got)
+			}
+		})
+	}
+}
+
+func TestSplitExcerpt(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      template.HTML
+		excerpt template.HTML
+		more    bool
+	}{
+		{"none", "<p>a</p><p>b</p>", "", false},
+		{"marker", "<p>a</p>\n<!--more-->\n<p>b</p>", "<p>a</p>", true},
+		{"spaced marker", "<p>a</p>\n<!--  more -->\n<p>b</p>", "<p>a</p>", true},
+		{"first of two", "<p>a</p><!--more--><p>b</p><!--more--><p>c</p>", "<p>a</p>", true},
+		{"other comment", "<p>a</p><!-- more to come --><p>b</p>", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			excerpt, more := splitExcerpt(tt.in)
+			if excerpt != tt.excerpt || more != tt.more {
+				t.Errorf("splitExcerpt = %q, %v, want %q, %v", excerpt, more, tt.excerpt, tt.more)
+			}
+		})
+	}
+}
+
+func TestPostSummary(t *testing.T) {
+	bp := &BlogPost{Body: "<p>Before the fold.</p>\n<!--more-->\n<p>After the fold.</p>"}
+	bp.bodyLoaded()
+	if got := bp.summary(200); got != "Before the fold." {
+		t.Errorf("with more = %q", got)
+	}
+
+	bp.Body = "<p>No fold &amp; all of it.</p>"
+	bp.bodyLoaded()
+	if got := bp.summary(200); got != "No fold & all of it." {
+		t.Errorf("without more = %q", got)
+	}
+}
+
+func TestBodyStats(t *testing.T) {
+	st := bodyStats(`<p>One two, three!</p><img src="a.png"><pre><code>not counted</code></pre><script>nor this</script><p>— four</p>`)
+	want := PostStats{Words: 4, ReadingTime: 1, Images: 1, CodeBlocks: 1}
+	if st != want {
+		t.Errorf("bodyStats = %+v, want %+v", st, want)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ReportOutput - a file in the built site and where it came from
+type ReportOutput struct {
+	File    string `json:"file"`
+	Source  string `json:"source,omitempty"`
+	Bytes   int64  `json:"bytes"`
+	Section string `json:"section"`
+}
+
+// ReportSection - time taken and output produced by one part of the build
+type ReportSection struct {
+	Name  string  `json:"name"`
+	Ms    float64 `json:"ms"`
+	Files int     `json:"files"`
+	Bytes int64   `json:"bytes"`
+}
+
+// ReportPost - a blog or micro post and what is in it
+type ReportPost struct {
+	Key   string `json:"key"`
+	Link  string `json:"link"`
+	Title string `json:"title"`
+	PostStats
+}
+
+// BuildReport - machine readable summary of a build, saved as build-report.json.
+// A nil report records nothing.
+type BuildReport struct {
+	Started  time.Time        `json:"started"`
+	Ms       float64          `json:"ms"`
+	Errors   int              `json:"errors"`
+	Warnings int              `json:"warnings"`
+	Files    int              `json:"files"`
+	Bytes    int64            `json:"bytes"`
+	Sections []*ReportSection `json:"sections"`
+	Outputs  []*ReportOutput  `json:"outputs"`
+	Posts    []*ReportPost    `json:"posts,omitempty"`
+
+	outputs map[string]*ReportOutput
+	lock    sync.Mutex
+}
+
+const reportFile = "build-report.json"
+
+var (
+	buildReport    *BuildReport
+	currentSection string
+)
+
+func newBuildReport() *BuildReport {
+	return &BuildReport{
+		Started: time.Now(),
+		outputs: make(map[string]*ReportOutput),
+	}
+}
+
+func sinceMs(start time.Time) float64 {
+	return float64(time.Since(start).Microseconds()) / 1000
+}
+
+// Output - note which section produced a file and from what
+func (r *BuildReport) Output(rel string, section string, source string) {
+	if r == nil {
+		return
+	}
+
+	key := outputKey(rel)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	o, ok := r.outputs[key]
+	if !ok {
+		o = &ReportOutput{File: key}
+		r.outputs[key] = o
+	}
+	if o.Section == "" {
+		o.Section = section
+	}
+	if source != "" {
+		o.Source = filepath.ToSlash(source)
+	}
+}
+
+// Has - whether the build has made a file at rel so far
+func (r *BuildReport) Has(rel string) bool {
+	if r == nil {
+		return false
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	_, ok := r.outputs[outputKey(rel)]
+	return ok
+}
+
+func (r *BuildReport) Post(bp *BlogPost) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Posts = append(r.Posts, &ReportPost{bp.Key, bp.Link, bp.Title, bp.Stats})
+}
+
+func (r *BuildReport) Time(name string, start time.Time) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Sections = append(r.Sections, &ReportSection{Name: name, Ms: sinceMs(start)})
+}
+
+// Finish - size up everything in the output folder, sources the
+// generators didn't give are taken from the manifest
+func (r *BuildReport) Finish(root string, m *BuildManifest) {
+	if r == nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	root = filepath.Clean(root)
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		key := outputKey(rel)
+		o, ok := r.outputs[key]
+		if !ok {
+			o = &ReportOutput{File: key}
+		}
+		if o.Source == "" && m != nil {
+			if e, ok := m.Outputs[key]; ok && len(e.Sources) > 0 {
+				o.Source = filepath.ToSlash(e.Sources[0])
+			}
+		}
+
+		o.Bytes = info.Size()
+		r.Outputs = append(r.Outputs, o)
+		return nil
+	})
+	sort.Slice(r.Outputs, func(i, j int) bool { return r.Outputs[i].File < r.Outputs[j].File })
+	sort.Slice(r.Posts, func(i, j int) bool { return r.Posts[i].Link < r.Posts[j].Link })
+
+	sections := make(map[string]*ReportSection)
+	for _, s := range r.Sections {
+		sections[s.Name] = s
+	}
+	for _, o := range r.Outputs {
+		r.Files++
+		r.Bytes += o.Bytes
+		if s, ok := sections[o.Section]; ok {
+			s.Files++
+			s.Bytes += o.Bytes
+		}
+	}
+
+	r.Errors = buildErrors.Count()
+	r.Warnings = buildErrors.Warnings()
+	r.Ms = sinceMs(r.Started)
+}
+
+func (r *BuildReport) Save() error {
+	if r == nil {
+		return nil
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return saveJSONBlob(siteConfig.SrcPath(reportFile), r)
+}
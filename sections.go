@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Section - one part of the site. Every enabled section is loaded before any
+// is generated, the feed and sitemap are built from what the others contribute.
+type Section interface {
+	Name() string
+	Load() error
+	Generate() error
+	SiteMap() []SiteMapLink
+	FeedPosts() BlogList
+}
+
+// SectionFuncs - a Section made from plain functions, any of them can be nil
+type SectionFuncs struct {
+	ID         string
+	LoadFn     func() error
+	GenerateFn func() error
+	SiteMapFn  func() []SiteMapLink
+	FeedFn     func() BlogList
+}
+
+func (s *SectionFuncs) Name() string { return s.ID }
+
+func (s *SectionFuncs) Load() error {
+	if s.LoadFn == nil {
+		return nil
+	}
+	return s.LoadFn()
+}
+
+func (s *SectionFuncs) Generate() error {
+	if s.GenerateFn == nil {
+		return nil
+	}
+	return s.GenerateFn()
+}
+
+func (s *SectionFuncs) SiteMap() []SiteMapLink {
+	if s.SiteMapFn == nil {
+		return nil
+	}
+	return s.SiteMapFn()
+}
+
+func (s *SectionFuncs) FeedPosts() BlogList {
+	if s.FeedFn == nil {
+		return nil
+	}
+	return s.FeedFn()
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Registry
+
+var (
+	sectionRegistry = make(map[string]Section)
+	activeSections  []Section
+
+	defaultSections      = []string{"gallery", "micro", "blog", "hobby", "job", "about", "feed", "sitemap", "redirects"}
+	defaultServeSections = []string{"gallery", "micro", "feed"}
+)
+
+func RegisterSection(s Section) {
+	sectionRegistry[s.Name()] = s
+}
+
+func init() {
+	RegisterSection(&SectionFuncs{
+		ID:         "gallery",
+		LoadFn:     LoadFromGalleryListFolder,
+		GenerateFn: GenerateGallery,
+		SiteMapFn:  gallerySiteMap,
+	})
+	RegisterSection(&SectionFuncs{
+		ID:         "micro",
+		LoadFn:     LoadFromMicroListFolder,
+		GenerateFn: GenerateMicro,
+	})
+	RegisterSection(&SectionFuncs{
+		ID:         "blog",
+		LoadFn:     LoadBlog,
+		GenerateFn: GenerateBlog,
+		SiteMapFn:  blogSiteMap,
+		FeedFn:     blogFeedPosts,
+	})
+	RegisterSection(&SectionFuncs{
+		ID:         "hobby",
+		LoadFn:     func() error { return genData.Hobby.LoadFromFile() },
+		GenerateFn: GenerateHobby,
+	})
+	RegisterSection(&SectionFuncs{
+		ID:         "job",
+		LoadFn:     LoadJobs,
+		GenerateFn: GenerateJob,
+	})
+	RegisterSection(&SectionFuncs{
+		ID:         "about",
+		GenerateFn: GenerateAbout,
+		SiteMapFn:  func() []SiteMapLink { return []SiteMapLink{indexSiteMapLink("/")} },
+	})
+	RegisterSection(&SectionFuncs{
+		ID:         "feed",
+		GenerateFn: GenerateFeed,
+	})
+	RegisterSection(&SectionFuncs{
+		ID:         "sitemap",
+		GenerateFn: GenerateSiteMap,
+	})
+	RegisterSection(&SectionFuncs{
+		ID:         "redirects",
+		GenerateFn: func() error { return GenerateRedirects(genData.Feed) },
+	})
+}
+
+// Config pages are looked up first so they can replace a built in section
+func findSection(name string) Section {
+	for i := range siteConfig.Pages {
+		if siteConfig.Pages[i].Name == name {
+			return &pageSection{&siteConfig.Pages[i]}
+		}
+	}
+	return sectionRegistry[name]
+}
+
+func resolveSections(names []string) ([]Section, error) {
+	var sections []Section
+	for _, name := range names {
+		s := findSection(name)
+		if s == nil {
+			return nil, fmt.Errorf("unknown section %q", name)
+		}
+		sections = append(sections, s)
+	}
+	return sections, nil
+}
+
+// Sections built by a full build, config pages go in before the feed by default
+func enabledSections() ([]Section, error) {
+	if len(siteConfig.Sections) > 0 {
+		return resolveSections(siteConfig.Sections)
+	}
+
+	var names []string
+	for _, name := range defaultSections {
+		if name == "feed" {
+			for _, p := range siteConfig.Pages {
+				names = append(names, p.Name)
+			}
+		}
+		names = append(names, name)
+	}
+	return resolveSections(names)
+}
+
+// Sections the server builds when it isn't asked for a full build
+func serveSections() ([]Section, error) {
+	if len(siteConfig.ServeSections) > 0 {
+		return resolveSections(siteConfig.ServeSections)
+	}
+	return resolveSections(defaultServeSections)
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Contributions
+
+func activeSiteMap() []SiteMapLink {
+	var links []SiteMapLink
+	for _, s := range activeSections {
+		links = append(links, s.SiteMap()...)
+	}
+
+	// Index pages first
+	sort.SliceStable(links, func(i, j int) bool { return links[i].Priority > links[j].Priority })
+	return links
+}
+
+func activeFeedPosts() BlogList {
+	var posts BlogList
+	for _, s := range activeSections {
+		posts = append(posts, s.FeedPosts()...)
+	}
+	sort.Sort(posts)
+	return posts
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Page - a single template rendered in the frame, added from config
+
+type pageSection struct {
+	cfg *PageConfig
+}
+
+func (p *pageSection) Name() string { return p.cfg.Name }
+
+func (p *pageSection) Load() error { return nil }
+
+func (p *pageSection) Generate() error {
+	log.Println("Page", p.cfg.Template, "->", p.cfg.Output)
+	return renderTemplatePage(p.cfg.Template, p.cfg.Output, p.cfg.Title, p.URL())
+}
+
+func (p *pageSection) URL() string {
+	dir := path.Dir("/" + strings.TrimPrefix(p.cfg.Output, "/"))
+	return strings.TrimSuffix(dir, "/") + "/"
+}
+
+func (p *pageSection) SiteMap() []SiteMapLink {
+	return []SiteMapLink{{
+		Loc:        p.URL(),
+		LastMod:    time.Now(),
+		Changefreq: "monthly",
+		Priority:   0.5,
+	}}
+}
+
+func (p *pageSection) FeedPosts() BlogList { return nil }
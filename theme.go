@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template/parse"
+)
+
+// Built in templates, used for anything the site and theme don't provide
+//
+//go:embed defaulttheme
+var embeddedTheme embed.FS
+
+var errTemplateNotFound = errors.New("template not found")
+
+type themeLayer struct {
+	Name string
+	FS   fs.FS
+}
+
+type themeEntry struct {
+	temp *template.Template
+	err  error
+}
+
+var (
+	themeCache = make(map[string]themeEntry)
+	themeLock  sync.Mutex
+)
+
+func templateDir() string {
+	return siteConfig.SrcDirPath(siteConfig.TemplateDir)
+}
+
+func themePath() string {
+	return filepath.Join(siteConfig.SrcDirPath(siteConfig.ThemesDir), siteConfig.Theme)
+}
+
+// Site templates override the theme which overrides the built in defaults
+func themeLayers() []themeLayer {
+	layers := []themeLayer{{templateDir(), os.DirFS(templateDir())}}
+	if siteConfig.Theme != "" {
+		layers = append(layers, themeLayer{themePath(), os.DirFS(themePath())})
+	}
+
+	defaults, _ := fs.Sub(embeddedTheme, "defaulttheme")
+	return append(layers, themeLayer{"(built in)", defaults})
+}
+
+// Read the first copy of a template found along the chain
+func readThemeFile(name string) ([]byte, string, error) {
+	for _, l := range themeLayers() {
+		b, err := fs.ReadFile(l.FS, name)
+		if err == nil {
+			return b, path.Join(l.Name, name), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+
+	return nil, "", fmt.Errorf("%w: %s in %s, the theme or the built in defaults", errTemplateNotFound, name, templateDir())
+}
+
+// Every template name available from any layer
+func themeFiles() []string {
+	seen := make(map[string]bool)
+	for _, l := range themeLayers() {
+		fs.WalkDir(l.FS, ".", func(p string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				seen[p] = true
+			}
+			return nil
+		})
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Hash the templates as they resolve, so a site override changes every page
+func hashTheme(w io.Writer) {
+	for _, name := range themeFiles() {
+		io.WriteString(w, name)
+		if b, _, err := readThemeFile(name); err == nil {
+			w.Write(b)
+		}
+	}
+}
+
+// Forget parsed templates so the next build picks up edits
+func resetTemplates() {
+	themeLock.Lock()
+	defer themeLock.Unlock()
+	themeCache = make(map[string]themeEntry)
+}
+
+// Layouts and partials are shared by every page
+func isSharedTemplate(name string) bool {
+	return strings.HasPrefix(name, "layouts/") || strings.HasPrefix(name, "partials/")
+}
+
+func parseThemeFile(t *template.Template, name string) error {
+	b, origin, err := readThemeFile(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = t.New(name).Parse(string(b))
+	if err != nil {
+		return fmt.Errorf("%s: %w", origin, err)
+	}
+	return nil
+}
+
+// The shared set, call with themeLock held
+func sharedTemplates() (*template.Template, error) {
+	if e, ok := themeCache[""]; ok {
+		return e.temp, e.err
+	}
+
+	var e themeEntry
+	e.temp = template.New("").Funcs(templateFuncs)
+	for _, name := range themeFiles() {
+		if isSharedTemplate(name) {
+			if e.err = parseThemeFile(e.temp, name); e.err != nil {
+				break
+			}
+		}
+	}
+
+	themeCache[""] = e
+	return e.temp, e.err
+}
+
+// Parse a page template into its own copy of the shared set the first time it is needed
+func themeTemplate(name string) (*template.Template, error) {
+	themeLock.Lock()
+	defer themeLock.Unlock()
+
+	if e, ok := themeCache[name]; ok {
+		return e.temp, e.err
+	}
+
+	var e themeEntry
+	shared, err := sharedTemplates()
+	if err == nil {
+		e.temp, err = shared.Clone()
+	}
+	if err == nil {
+		err = parseThemeFile(e.temp, name)
+	}
+	e.err = err
+
+	themeCache[name] = e
+	return e.temp, e.err
+}
+
+// A page made only of defines fills in the blocks of a layout,
+// anything else is an old style page rendered on its own inside root.html
+func isLayoutPage(t *template.Template) bool {
+	if t == nil || t.Tree == nil {
+		return false
+	}
+
+	for _, n := range t.Tree.Root.Nodes {
+		text, ok := n.(*parse.TextNode)
+		if !ok || len(bytes.TrimSpace(text.Text)) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Pages use layouts/base.html unless they define "layout" as the name of another
+func pageLayout(set *template.Template, frame *SubPage) (string, error) {
+	t := set.Lookup("layout")
+	if t == nil {
+		return "layouts/base.html", nil
+	}
+
+	var b bytes.Buffer
+	err := t.Execute(&b, frame)
+	return "layouts/" + strings.TrimSpace(b.String()) + ".html", err
+}
+
+// Render a page template inside its layout
+func renderPage(w *bytes.Buffer, name string, data interface{}, frame *SubPage) error {
+	set, err := themeTemplate(name)
+	if err != nil {
+		return err
+	}
+
+	if isLayoutPage(set.Lookup(name)) {
+		frame.Data = data
+		layout, err := pageLayout(set, frame)
+		if err == nil {
+			err = set.ExecuteTemplate(w, layout, frame)
+		}
+		if err != nil {
+			return fmt.Errorf("error in template: %w", err)
+		}
+		return nil
+	}
+
+	var content bytes.Buffer
+	err = set.ExecuteTemplate(&content, name, data)
+	if err != nil {
+		return fmt.Errorf("error in template: %w", err)
+	}
+	frame.Content = template.HTML(content.String())
+
+	// Old style frame if the site or theme still has one
+	root, err := themeTemplate("root.html")
+	if errors.Is(err, errTemplateNotFound) {
+		root, err = set, nil
+		name = "layouts/base.html"
+	} else {
+		name = "root.html"
+	}
+	if err == nil {
+		err = root.ExecuteTemplate(w, name, frame)
+	}
+	if err != nil {
+		return fmt.Errorf("error in template: %w", err)
+	}
+	return nil
+}
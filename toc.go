@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// TOCEntry - a heading in a post, with the smaller headings under it
+type TOCEntry struct {
+	Level    int
+	ID       string
+	Title    string
+	Children []*TOCEntry
+}
+
+// Posts with fewer headings than this are short enough to go without a contents list
+const tocMinHeadings = 3
+
+func isTOCHeading(a atom.Atom) bool {
+	return a == atom.H2 || a == atom.H3 || a == atom.H4
+}
+
+func isAnchor(tok html.Token) bool {
+	if tok.DataAtom != atom.A {
+		return false
+	}
+	for _, a := range tok.Attr {
+		if a.Key == "class" && strings.Contains(" "+a.Val+" ", " anchor ") {
+			return true
+		}
+	}
+	return false
+}
+
+// Every id already in the body, so a slug can't take one used further down
+func existingIDs(body template.HTML) map[string]bool {
+	used := make(map[string]bool)
+
+	z := html.NewTokenizer(strings.NewReader(string(body)))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return used
+		}
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		for _, a := range z.Token().Attr {
+			if a.Key == "id" {
+				used[a.Val] = true
+			}
+		}
+	}
+}
+
+// Give every h2 to h4 an id and a permalink anchor, leaving the rest of the html as written.
+// Headings keep an id they already have, the rest get a slug of their text. Headings that
+// already have an anchor are left alone, so running it again changes nothing
+func addHeadingAnchors(body template.HTML) (template.HTML, []*TOCEntry) {
+	var out, heading bytes.Buffer
+	var open *html.Token
+	var text strings.Builder
+	var flat []*TOCEntry
+	hasAnchor, inAnchor := false, false
+	used := existingIDs(body)
+
+	z := html.NewTokenizer(strings.NewReader(string(body)))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return body, nil
+			}
+			break
+		}
+
+		raw := append([]byte{}, z.Raw()...)
+		tok := z.Token()
+
+		switch {
+		case open == nil && tt == html.StartTagToken && isTOCHeading(tok.DataAtom):
+			open = &tok
+			heading.Reset()
+			text.Reset()
+			hasAnchor, inAnchor = false, false
+			continue
+
+		case open != nil && tt == html.EndTagToken && tok.DataAtom == open.DataAtom:
+			title := strings.Join(strings.Fields(text.String()), " ")
+			id := ""
+			for _, a := range open.Attr {
+				if a.Key == "id" {
+					id = a.Val
+				}
+			}
+			if id == "" {
+				id = uniqueID(slugify(title), used)
+				open.Attr = append(open.Attr, html.Attribute{Key: "id", Val: id})
+			}
+			used[id] = true
+
+			out.WriteString(open.String())
+			out.Write(heading.Bytes())
+			if !hasAnchor {
+				fmt.Fprintf(&out, `<a class="anchor" href="#%s" aria-hidden="true">#</a>`, template.HTMLEscapeString(id))
+			}
+			out.Write(raw)
+
+			flat = append(flat, &TOCEntry{
+				Level: int(open.Data[1] - '0'),
+				ID:    id,
+				Title: title,
+			})
+			open = nil
+			continue
+
+		case open != nil:
+			switch {
+			case tt == html.StartTagToken && isAnchor(tok):
+				hasAnchor, inAnchor = true, true
+			case tt == html.EndTagToken && tok.DataAtom == atom.A:
+				inAnchor = false
+			case tt == html.TextToken && !inAnchor:
+				text.WriteString(tok.Data)
+			}
+			heading.Write(raw)
+			continue
+		}
+
+		out.Write(raw)
+	}
+
+	// Unclosed heading, leave the body alone
+	if open != nil {
+		return body, nil
+	}
+
+	return template.HTML(out.String()), nestTOC(flat)
+}
+
+func uniqueID(id string, used map[string]bool) string {
+	if id == "" {
+		id = "section"
+	}
+
+	try := id
+	for n := 2; used[try]; n++ {
+		try = fmt.Sprintf("%s-%d", id, n)
+	}
+	return try
+}
+
+// Each heading goes under the closest bigger heading before it
+func nestTOC(flat []*TOCEntry) []*TOCEntry {
+	var root, stack []*TOCEntry
+	for _, e := range flat {
+		for len(stack) > 0 && stack[len(stack)-1].Level >= e.Level {
+			stack = stack[:len(stack)-1]
+		}
+
+		if len(stack) == 0 {
+			root = append(root, e)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, e)
+		}
+		stack = append(stack, e)
+	}
+	return root
+}
+
+func countTOC(entries []*TOCEntry) int {
+	n := len(entries)
+	for _, e := range entries {
+		n += countTOC(e.Children)
+	}
+	return n
+}
+
+// HasTOC - long enough for blogpost.html to show the contents
+func (bp *BlogPost) HasTOC() bool {
+	return countTOC(bp.TOC) >= tocMinHeadings
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"html/template"
+	"reflect"
+	"testing"
+)
+
+// Id of each entry, with its children in brackets after it
+func tocShape(entries []*TOCEntry) []string {
+	var shape []string
+	for _, e := range entries {
+		shape = append(shape, e.ID)
+		if len(e.Children) > 0 {
+			shape = append(shape, "[")
+			shape = append(shape, tocShape(e.Children)...)
+			shape = append(shape, "]")
+		}
+	}
+	return shape
+}
+
+func TestAddHeadingAnchorsIDs(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		ids    []string
+		titles []string
+	}{
+		{
+			name:   "slug of the text",
+			in:     `<h2>Hello, World!</h2>`,
+			ids:    []string{"hello-world"},
+			titles: []string{"Hello, World!"},
+		},
+		{
+			name:   "duplicates numbered",
+			in:     `<h2>Setup</h2><h3>Setup</h3><h3>Setup</h3>`,
+			ids:    []string{"setup", "setup-2", "setup-3"},
+			titles: []string{"Setup", "Setup", "Setup"},
+		},
+		{
+			name:   "existing id kept and reserved",
+			in:     `<h2 id="setup">First</h2><h2>Setup</h2>`,
+			ids:    []string{"setup", "setup-2"},
+			titles: []string{"First", "Setup"},
+		},
+		{
+			name:   "later id reserved",
+			in:     `<h2>Setup</h2><h2 id="setup">Second</h2>`,
+			ids:    []string{"setup-2", "setup"},
+			titles: []string{"Setup", "Second"},
+		},
+		{
+			name:   "ids outside headings reserved",
+			in:     `<h2>Intro</h2><p id="intro">Text</p><img id="section"><h2></h2>`,
+			ids:    []string{"intro-2", "section-2"},
+			titles: []string{"Intro", ""},
+		},
+		{
+			name:   "no text",
+			in:     `<h2><img src="a.png"></h2><h2>!!</h2>`,
+			ids:    []string{"section", "section-2"},
+			titles: []string{"", "!!"},
+		},
+		{
+			name:   "inline markup and whitespace",
+			in:     "<h3>The <code>Go</code>\n  way</h3>",
+			ids:    []string{"the-go-way"},
+			titles: []string{"The Go way"},
+		},
+		{
+			name: "other headings left alone",
+			in:   `<h1>Title</h1><h5>Small</h5>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, toc := addHeadingAnchors(template.HTML(tt.in))
+
+			var ids, titles []string
+			var walk func([]*TOCEntry)
+			walk = func(entries []*TOCEntry) {
+				for _, e := range entries {
+					ids = append(ids, e.ID)
+					titles = append(titles, e.Title)
+					walk(e.Children)
+				}
+			}
+			walk(toc)
+
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("ids = %q, want %q", ids, tt.ids)
+			}
+			if !reflect.DeepEqual(titles, tt.titles) {
+				t.Errorf("titles = %q, want %q", titles, tt.titles)
+			}
+		})
+	}
+}
+
+func TestAddHeadingAnchorsOutput(t *testing.T) {
+	body, _ := addHeadingAnchors(`<p>Intro</p><h2 class="big">A &amp; B</h2><p>Text</p>`)
+	want := `<p>Intro</p><h2 class="big" id="a-b">A &amp; B<a class="anchor" href="#a-b" aria-hidden="true">#</a></h2><p>Text</p>`
+	if string(body) != want {
+		t.Errorf("body = %s\nwant   %s", body, want)
+	}
+}
+
+func TestAddHeadingAnchorsTwice(t *testing.T) {
+	in := template.HTML(`<h2>One</h2><p>x</p><h3>Two</h3><h2 id="three">Three</h2>`)
+
+	once, toc1 := addHeadingAnchors(in)
+	twice, toc2 := addHeadingAnchors(once)
+	if once != twice {
+		t.Errorf("second run changed the body\n%s\n%s", once, twice)
+	}
+	if !reflect.DeepEqual(toc1, toc2) {
+		t.Errorf("second run changed the contents\n%v\n%v", tocShape(toc1), tocShape(toc2))
+	}
+}
+
+func TestAddHeadingAnchorsUnclosed(t *testing.T) {
+	in := template.HTML(`<h2>Never closed<p>text</p>`)
+	body, toc := addHeadingAnchors(in)
+	if body != in || toc != nil {
+		t.Errorf("got %s %v", body, toc)
+	}
+}
+
+func TestNestTOC(t *testing.T) {
+	tests := []struct {
+		name   string
+		levels []int
+		shape  []string
+	}{
+		{"flat", []int{2, 2, 2}, []string{"a", "b", "c"}},
+		{"nested", []int{2, 3, 4, 3, 2}, []string{"a", "[", "b", "[", "c", "]", "d", "]", "e"}},
+		{"starts deep", []int{3, 2, 3}, []string{"a", "b", "[", "c", "]"}},
+		{"skips a level", []int{2, 4, 3}, []string{"a", "[", "b", "c", "]"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var flat []*TOCEntry
+			for i, l := range tt.levels {
+				flat = append(flat, &TOCEntry{Level: l, ID: string(rune('a' + i))})
+			}
+
+			toc := nestTOC(flat)
+			if shape := tocShape(toc); !reflect.DeepEqual(shape, tt.shape) {
+				t.Errorf("shape = %v, want %v", shape, tt.shape)
+			}
+			if n := countTOC(toc); n != len(flat) {
+				t.Errorf("countTOC = %d, want %d", n, len(flat))
+			}
+		})
+	}
+}
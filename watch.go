@@ -0,0 +1,110 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Source folders that feed the site
+var watchedFolders = []string{"blogdata", "microdata", "gallery", "Data", "Templates", "static_folder", "images"}
+
+type fileStamp struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// Watcher - polls the source folders and reports which of them changed
+type Watcher struct {
+	Interval time.Duration
+	Changes  chan map[string]bool
+
+	stamps map[string]fileStamp
+}
+
+func MakeWatcher(interval time.Duration) *Watcher {
+	w := &Watcher{
+		Interval: interval,
+		Changes:  make(chan map[string]bool),
+	}
+
+	w.stamps = w.scan()
+	go w.PollLoop()
+
+	return w
+}
+
+// Sidecar meta data and the redirect ledger are written by builds so would retrigger forever
+func isGeneratedSidecar(folder string, path string) bool {
+	if folder == "blogdata" && filepath.Base(path) == redirectLedgerFile {
+		return true
+	}
+	return (folder == "microdata" || folder == "gallery") && strings.HasSuffix(path, ".json")
+}
+
+// Templates come from the site overrides and the theme, not the source folder
+func watchRoots(folder string) []string {
+	if folder != "Templates" {
+		return []string{siteConfig.SrcPath(folder)}
+	}
+
+	roots := []string{templateDir()}
+	if siteConfig.Theme != "" {
+		roots = append(roots, themePath())
+	}
+	return roots
+}
+
+func (w *Watcher) scan() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+
+	for _, folder := range watchedFolders {
+		for _, root := range watchRoots(folder) {
+			filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+				if err != nil || info.IsDir() || isGeneratedSidecar(folder, path) {
+					return nil
+				}
+				stamps[folder+"|"+path] = fileStamp{info.ModTime(), info.Size()}
+				return nil
+			})
+		}
+	}
+
+	return stamps
+}
+
+func (w *Watcher) PollLoop() {
+	for {
+		time.Sleep(w.Interval)
+
+		stamps := w.scan()
+		changed := make(map[string]bool)
+
+		for k, s := range stamps {
+			if old, ok := w.stamps[k]; !ok || old != s {
+				changed[strings.SplitN(k, "|", 2)[0]] = true
+			}
+		}
+		for k := range w.stamps {
+			if _, ok := stamps[k]; !ok {
+				changed[strings.SplitN(k, "|", 2)[0]] = true
+			}
+		}
+
+		w.stamps = stamps
+		if len(changed) > 0 {
+			w.Changes <- changed
+		}
+	}
+}
+
+// Even a static file change goes through the staged build so deleted files get pruned,
+// the manifest keeps pages that didn't change from being rendered again
+func regenerateFor(changed map[string]bool) {
+	log.Println("Changes in", changed, "regenerating")
+	if err := Generate(); err != nil {
+		errorLog.Println(err)
+	}
+}
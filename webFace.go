@@ -1,32 +1,48 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 )
 
 type WebFace struct {
-	Addr   string
-	Router *http.ServeMux
+	Addr     string
+	Router   *http.ServeMux
+	HostRoot string
 
 	OutMsg             chan string
 	InMsg              chan string
 	GlobalTemplateData map[string]string
+	LiveReload         bool
+
+	fileServer    http.Handler
+	reloadClients map[chan string]bool
+	reloadLock    sync.Mutex
 }
 
-func MakeWebFace(addr string, hostfileroot string) *WebFace {
+func MakeWebFace(addr string, hostfileroot string, liveReload bool) *WebFace {
 	w := &WebFace{
-		Addr:   addr,
-		Router: http.NewServeMux(),
+		Addr:       addr,
+		Router:     http.NewServeMux(),
+		HostRoot:   hostfileroot,
+		LiveReload: liveReload,
 
 		OutMsg:             make(chan string),
 		InMsg:              make(chan string),
 		GlobalTemplateData: make(map[string]string),
+
+		fileServer:    http.FileServer(http.Dir(hostfileroot)),
+		reloadClients: make(map[chan string]bool),
 	}
 
 	w.MakeTemplates()
@@ -35,7 +51,9 @@ func MakeWebFace(addr string, hostfileroot string) *WebFace {
 	w.Router.HandleFunc("/admin/blog/", w.ServeBlogPage)
 	w.Router.HandleFunc("/admin/generate", w.ServeGenerate)
 	w.Router.HandleFunc("/admin/", w.ServeAdminPage)
-	w.Router.Handle("/", http.FileServer(http.Dir(hostfileroot)))
+	w.Router.HandleFunc("/livereload", w.ServeLiveReload)
+	w.Router.HandleFunc("/livereload.js", w.ServeLiveReloadScript)
+	w.Router.HandleFunc("/", w.ServeSite)
 
 	go w.HostLoop()
 
@@ -47,7 +65,7 @@ var AdminTemplate, EditTemplate, ListTemplate *template.Template
 
 func (wf *WebFace) MakeTemplates() {
 	var err error
-	ListTemplate, err = template.New("list").Parse(`<!DOCTYPE html>
+	ListTemplate, err = template.New("list").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
 <html>
 <head>
   <title>Blog Listing</title>
@@ -84,6 +102,7 @@ func (wf *WebFace) MakeTemplates() {
 {{end}}
 
 <td style="width: 300px;">{{.ShortDesc}}</td>
+<td>{{.Stats.Words}} words<br>{{.Stats.ReadingTime}} min<br>{{.Stats.Images}} images<br>{{.Stats.CodeBlocks}} code</td>
 </tr>
 {{end}}
 </table>
@@ -95,7 +114,7 @@ func (wf *WebFace) MakeTemplates() {
 		CheckErr(err)
 	}
 
-	EditTemplate, err = template.New("edit").Parse(`<!DOCTYPE html>
+	EditTemplate, err = template.New("edit").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
 <html>
 <head>
   <title>Editing {{.Key}}</title>
@@ -127,7 +146,7 @@ func (wf *WebFace) MakeTemplates() {
 		CheckErr(err)
 	}
 
-	AdminTemplate, err = template.New("admin").Parse(`<!DOCTYPE html>
+	AdminTemplate, err = template.New("admin").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
 <html>
 <head>
   <title>Admin</title>
@@ -151,19 +170,31 @@ var validBlogPath = regexp.MustCompile(`^/admin/blog/([a-zA-Z0-9\\-]+)/(edit|sav
 
 func (wf *WebFace) ServeBlogPage(w http.ResponseWriter, req *http.Request) {
 	m := validBlogPath.FindStringSubmatch(req.URL.Path)
+	if m == nil || genData == nil {
+		http.NotFound(w, req)
+		return
+	}
 
 	// Get Page
 	b := genData.Feed.Get(m[1])
+	if b == nil {
+		http.NotFound(w, req)
+		return
+	}
 
 	switch m[2] {
 	case "edit":
 		err := EditTemplate.ExecuteTemplate(w, "edit", b)
 		if err != nil {
-			CheckErr(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
 
 	case "save":
-		log.Fatalln("This is fucked up don't use it esp save cause of microdata")
+		if b.IsMicro {
+			http.Error(w, "micro posts are edited in microdata", http.StatusBadRequest)
+			return
+		}
+		oldBody, oldLink := b.bodyFile(), b.Link
 
 		b.Title = req.FormValue("Title")
 		catList := strings.Split(strings.TrimRight(req.FormValue("RawCategory"), " ,"), ",")
@@ -185,9 +216,31 @@ func (wf *WebFace) ServeBlogPage(w http.ResponseWriter, req *http.Request) {
 		b.BannerImage = req.FormValue("BannerImage")
 		b.ShortDesc = req.FormValue("ShortDesc")
 		b.Body = template.HTML(req.FormValue("Body"))
+		b.bodyLoaded()
+
+		if err := b.SaveBodyToFile(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// The body follows the date into another year's folder
+		if b.bodyFile() != oldBody {
+			os.Remove(oldBody)
+		}
+		if err := b.SaveToIndex(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		b.preparePage()
+		if err := b.GeneratePage(); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-		genData.Feed.SaveToFile()
-		b.GeneratePage()
+		// A new date moves the post, rebuild so the indexes and redirects follow
+		if b.Link != oldLink {
+			wf.InMsg <- "generate"
+		}
 
 		http.Redirect(w, req, "/admin/blog/"+m[1]+"/edit", http.StatusFound)
 
@@ -208,15 +261,117 @@ func (wf *WebFace) ServeAdminPage(w http.ResponseWriter, req *http.Request) {
 
 	err := AdminTemplate.ExecuteTemplate(w, "admin", wf.GlobalTemplateData)
 	if err != nil {
-		CheckErr(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
 func (wf *WebFace) ServeBlogList(w http.ResponseWriter, req *http.Request) {
 
+	if genData == nil {
+		http.NotFound(w, req)
+		return
+	}
+
 	err := ListTemplate.ExecuteTemplate(w, "list", genData.Feed)
 	if err != nil {
-		CheckErr(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// //////////////////////////////////////////////////////////////////////////////
+// Live Reload - pages get a script that listens for reload events
+
+const liveReloadScript = `(function() {
+  var source = new EventSource("/livereload");
+  source.onmessage = function(e) {
+    if (e.data === "reload") {
+      window.location.reload();
+    }
+  };
+})();
+`
+
+var liveReloadTag = []byte(`<script src="/livereload.js"></script>`)
+
+func (wf *WebFace) ServeSite(w http.ResponseWriter, req *http.Request) {
+	file := filepath.Join(wf.HostRoot, filepath.FromSlash(path.Clean("/"+req.URL.Path)))
+	if strings.HasSuffix(req.URL.Path, "/") {
+		file = filepath.Join(file, "index.html")
+	}
+
+	if !wf.LiveReload || filepath.Ext(file) != ".html" {
+		wf.fileServer.ServeHTTP(w, req)
+		return
+	}
+
+	body, err := os.ReadFile(file)
+	if err != nil {
+		wf.fileServer.ServeHTTP(w, req)
+		return
+	}
+
+	i := bytes.LastIndex(body, []byte("</body>"))
+	if i < 0 {
+		i = len(body)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(body[:i])
+	w.Write(liveReloadTag)
+	w.Write(body[i:])
+}
+
+func (wf *WebFace) ServeLiveReloadScript(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/javascript")
+	fmt.Fprint(w, liveReloadScript)
+}
+
+func (wf *WebFace) ServeLiveReload(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	c := make(chan string, 1)
+	wf.reloadLock.Lock()
+	wf.reloadClients[c] = true
+	wf.reloadLock.Unlock()
+
+	defer func() {
+		wf.reloadLock.Lock()
+		delete(wf.reloadClients, c)
+		wf.reloadLock.Unlock()
+	}()
+
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case m := <-c:
+			fmt.Fprintf(w, "data: %s\n\n", m)
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
+// Reload - tell every open page to reload
+func (wf *WebFace) Reload() {
+	wf.reloadLock.Lock()
+	defer wf.reloadLock.Unlock()
+
+	for c := range wf.reloadClients {
+		select {
+		case c <- "reload":
+		default:
+		}
 	}
 }
 